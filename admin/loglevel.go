@@ -0,0 +1,66 @@
+package admin
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/flockiot/flock-api/logging"
+)
+
+type logLevels struct {
+	Level      string            `json:"level,omitempty"`
+	Components map[string]string `json:"components"`
+}
+
+func currentLogLevels() logLevels {
+	resp := logLevels{
+		Level:      levelName(logging.Level()),
+		Components: map[string]string{},
+	}
+	for name, lvl := range logging.ComponentLevels() {
+		resp.Components[name] = levelName(lvl)
+	}
+	return resp
+}
+
+func levelName(l slog.Level) string {
+	return strings.ToLower(l.String())
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func handleGetLogLevel(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, currentLogLevels())
+}
+
+// Changes last until a restart or a reload of the log settings.
+func handleSetLogLevel(w http.ResponseWriter, r *http.Request) {
+	var req logLevels
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		return
+	}
+	previous := logging.Level()
+	if req.Level != "" {
+		if err := logging.SetLevel(req.Level); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+	}
+	if req.Components != nil {
+		if err := logging.SetComponentLevels(req.Components); err != nil {
+			_ = logging.SetLevel(levelName(previous))
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+	}
+	resp := currentLogLevels()
+	slog.InfoContext(r.Context(), "log levels changed", "level", resp.Level, "components", resp.Components)
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,54 @@
+package admin
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/flockiot/flock-api/config"
+	"github.com/flockiot/flock-api/logging"
+)
+
+func TestLogLevelEndpoint(t *testing.T) {
+	if err := logging.Setup("info", "json", io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = logging.SetComponentLevels(nil) })
+	r := NewRouter(&config.Config{Auth: config.AuthConfig{AdminToken: "admin"}}, nil, nil)
+
+	do := func(method, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/log-level", strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := do(http.MethodPut, "", `{"level":"debug"}`); w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without a token, got %d", w.Code)
+	}
+	if w := do(http.MethodPut, "admin", `{"level":"debug","components":{"api":"loud"}}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an invalid level, got %d", w.Code)
+	}
+	if got := logging.Level().String(); got != "INFO" {
+		t.Fatalf("a rejected request changed the level to %s", got)
+	}
+
+	w := do(http.MethodPut, "admin", `{"level":"warn","components":{"repository":"debug"}}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	w = do(http.MethodGet, "admin", "")
+	var resp logLevels
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode: %v", err)
+	}
+	if resp.Level != "warn" || resp.Components["repository"] != "debug" || len(resp.Components) != 1 {
+		t.Fatalf("unexpected levels: %+v", resp)
+	}
+}
@@ -0,0 +1,88 @@
+package admin
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+
+	"github.com/flockiot/flock-api/auth"
+	"github.com/flockiot/flock-api/config"
+	"github.com/flockiot/flock-api/metrics"
+	"github.com/flockiot/flock-api/target"
+)
+
+// Start serves operator endpoints on their own port so they can be kept off
+// the public network.
+func Start(ctx context.Context, cfg *config.Config, sup *target.Supervisor, reloader *config.Reloader) error {
+	addr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.AdminPort)
+	srv := &http.Server{
+		Addr:    addr,
+		Handler: NewRouter(cfg, sup, reloader),
+		BaseContext: func(_ net.Listener) context.Context {
+			return ctx
+		},
+	}
+
+	slog.Info("admin server listening", "addr", addr)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.ListenAndServe()
+	}()
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Supervisor.ServerShutdownTimeout())
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		slog.Error("admin server shutdown timed out, closing connections", "error", err)
+		return srv.Close()
+	}
+	return nil
+}
+
+func NewRouter(cfg *config.Config, sup *target.Supervisor, reloader *config.Reloader) chi.Router {
+	r := chi.NewRouter()
+	r.Use(middleware.Recoverer)
+	r.Method(http.MethodGet, "/metrics", metrics.Handler())
+	r.Group(func(r chi.Router) {
+		r.Use(auth.NewAuthenticator(cfg.Auth.AdminToken, nil).Middleware)
+		r.Get("/log-level", handleGetLogLevel)
+		r.Put("/log-level", handleSetLogLevel)
+		if sup != nil {
+			r.Get("/targets", handleTargets(sup))
+		}
+		if reloader != nil {
+			r.Post("/reload", handleReload(reloader))
+		}
+	})
+	return r
+}
+
+func handleTargets(sup *target.Supervisor) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{"targets": sup.Statuses()})
+	}
+}
+
+func handleReload(reloader *config.Reloader) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		changes, err := reloader.Reload()
+		if err != nil {
+			slog.Error("configuration reload failed", "error", err)
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		if changes == nil {
+			changes = []config.Change{}
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"changes": changes})
+	}
+}
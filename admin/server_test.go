@@ -0,0 +1,106 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/flockiot/flock-api/config"
+	"github.com/flockiot/flock-api/target"
+	"github.com/flockiot/flock-api/version"
+)
+
+func TestMetricsEndpoint(t *testing.T) {
+	version.Value = "1.2.3"
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	w := httptest.NewRecorder()
+	NewRouter(&config.Config{}, nil, nil).ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), `flock_build_info{goversion="`) ||
+		!strings.Contains(w.Body.String(), `version="1.2.3"} 1`) {
+		t.Fatalf("expected build info in output, got:\n%s", w.Body.String())
+	}
+}
+
+func TestTargetsEndpoint(t *testing.T) {
+	sup := target.NewSupervisor(config.SupervisorConfig{}, &target.Deps{})
+	_ = sup.Run(context.Background(), []target.Spec{{Name: "worker", Start: func(context.Context, *target.Deps) error { return nil }}})
+
+	req := httptest.NewRequest(http.MethodGet, "/targets", nil)
+	w := httptest.NewRecorder()
+	req.Header.Set("Authorization", "Bearer admin")
+	NewRouter(&config.Config{Auth: config.AuthConfig{AdminToken: "admin"}}, sup, nil).ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var resp struct {
+		Targets []target.Status `json:"targets"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode: %v", err)
+	}
+	if len(resp.Targets) != 1 || resp.Targets[0].Name != "worker" || resp.Targets[0].State != target.StateStopped {
+		t.Fatalf("unexpected targets: %+v", resp.Targets)
+	}
+}
+
+func TestReloadEndpoint(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "flock.yaml")
+	write := func(content string) {
+		if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("log:\n  level: info\n")
+	opts := config.Options{File: file, Environ: []string{"FLOCK_AUTH_ADMIN_TOKEN=admin"}}
+	cfg, _, err := config.LoadWithOptions(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewRouter(cfg, nil, config.NewReloader(cfg, opts))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/reload", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without a token, got %d", w.Code)
+	}
+
+	reload := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/reload", nil)
+		req.Header.Set("Authorization", "Bearer admin")
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	write("log:\n  level: verbose\n")
+	if w := reload(); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "FLOCK_LOG_LEVEL") {
+		t.Fatalf("expected 400 naming FLOCK_LOG_LEVEL, got %d: %s", w.Code, w.Body.String())
+	}
+
+	write("log:\n  level: debug\n")
+	w = reload()
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Changes []config.Change `json:"changes"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode: %v", err)
+	}
+	want := config.Change{Setting: "log.level", Old: "info", New: "debug", Applied: true}
+	if len(resp.Changes) != 1 || resp.Changes[0] != want {
+		t.Fatalf("changes = %+v, want [%+v]", resp.Changes, want)
+	}
+}
@@ -0,0 +1,174 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/flockiot/flock-api/audit"
+	"github.com/flockiot/flock-api/auth"
+	"github.com/flockiot/flock-api/logging"
+	"github.com/flockiot/flock-api/repository"
+)
+
+type auditEventResponse struct {
+	ID             int64           `json:"id"`
+	OccurredAt     time.Time       `json:"occurred_at"`
+	ActorType      string          `json:"actor_type"`
+	ActorID        string          `json:"actor_id,omitempty"`
+	OrganizationID string          `json:"organization_id,omitempty"`
+	Action         string          `json:"action"`
+	ResourceType   string          `json:"resource_type"`
+	ResourceID     string          `json:"resource_id"`
+	Changes        json.RawMessage `json:"changes"`
+	RequestID      string          `json:"request_id,omitempty"`
+	SourceIP       string          `json:"source_ip,omitempty"`
+}
+
+func newAuditEventResponse(e *repository.AuditEvent) auditEventResponse {
+	return auditEventResponse{
+		ID:             e.ID,
+		OccurredAt:     e.OccurredAt,
+		ActorType:      e.ActorType,
+		ActorID:        e.ActorID,
+		OrganizationID: e.OrganizationID,
+		Action:         e.Action,
+		ResourceType:   e.ResourceType,
+		ResourceID:     e.ResourceID,
+		Changes:        e.Changes,
+		RequestID:      e.RequestID,
+		SourceIP:       e.SourceIP,
+	}
+}
+
+// auditContext lets repository mutations attribute audit events.
+func auditContext(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m := audit.Metadata{
+			Actor:     audit.Actor{Type: audit.ActorSystem},
+			RequestID: logging.RequestID(r.Context()),
+			SourceIP:  sourceIP(r),
+		}
+		if p, ok := auth.FromContext(r.Context()); ok {
+			if p.Admin {
+				m.Actor = audit.Actor{Type: audit.ActorAdmin}
+			} else {
+				m.Actor = audit.Actor{Type: audit.ActorAPIKey, ID: p.KeyID}
+			}
+		}
+		ctx := audit.WithMetadata(r.Context(), m)
+		ctx = logging.With(ctx, "actor", m.Actor)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func sourceIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+func requireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, ok := auth.FromContext(r.Context())
+		if !ok || !p.Admin {
+			writeError(w, http.StatusForbidden, "forbidden")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Routes nested under an organization are always scoped to it; only the
+// admin-wide routes accept organization_id.
+func parseAuditFilter(r *http.Request) (repository.AuditFilter, error) {
+	q := r.URL.Query()
+	f := repository.AuditFilter{
+		OrganizationID: chi.URLParam(r, "orgID"),
+		ActorID:        q.Get("actor_id"),
+		Action:         q.Get("action"),
+		ResourceType:   q.Get("resource_type"),
+		ResourceID:     q.Get("resource_id"),
+	}
+	if f.OrganizationID == "" {
+		f.OrganizationID = q.Get("organization_id")
+		if f.OrganizationID != "" && !repository.IsUUID(f.OrganizationID) {
+			return f, fmt.Errorf("organization_id must be a UUID")
+		}
+	}
+	for _, p := range []struct {
+		name string
+		dst  *time.Time
+	}{{"since", &f.Since}, {"until", &f.Until}} {
+		if v := q.Get(p.name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return f, fmt.Errorf("%s must be an RFC 3339 timestamp", p.name)
+			}
+			*p.dst = t
+		}
+	}
+	return f, nil
+}
+
+func auditRoutes(repo *repository.AuditRepository) func(chi.Router) {
+	return func(r chi.Router) {
+		r.Get("/", handleListAuditEvents(repo))
+		r.Get("/export", handleExportAuditEvents(repo))
+	}
+}
+
+func handleListAuditEvents(repo *repository.AuditRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		filter, err := parseAuditFilter(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		limit, offset, err := parsePagination(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		events, err := repo.List(r.Context(), filter, limit, offset)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "listing audit events failed", "error", err)
+			writeError(w, http.StatusInternalServerError, "internal error")
+			return
+		}
+		resp := make([]auditEventResponse, 0, len(events))
+		for _, e := range events {
+			resp = append(resp, newAuditEventResponse(e))
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"audit_events": resp})
+	}
+}
+
+func handleExportAuditEvents(repo *repository.AuditRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		filter, err := parseAuditFilter(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Header().Set("Content-Disposition", `attachment; filename="audit-events.ndjson"`)
+		w.WriteHeader(http.StatusOK)
+
+		enc := json.NewEncoder(w)
+		err = repo.Export(r.Context(), filter, func(e *repository.AuditEvent) error {
+			return enc.Encode(newAuditEventResponse(e))
+		})
+		if err != nil {
+			slog.ErrorContext(r.Context(), "exporting audit events failed", "error", err)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/flockiot/flock-api/audit"
+	"github.com/flockiot/flock-api/auth"
+)
+
+func TestAuditContextAttributesActor(t *testing.T) {
+	var got audit.Metadata
+	handler := auditContext(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		got = audit.FromContext(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.RemoteAddr = "203.0.113.7:51234"
+	req = req.WithContext(auth.WithPrincipal(req.Context(), &auth.Principal{KeyID: "key-1", OrganizationID: "org-1"}))
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if got.Actor.Type != audit.ActorAPIKey || got.Actor.ID != "key-1" {
+		t.Fatalf("expected api key actor, got %+v", got.Actor)
+	}
+	if got.SourceIP != "203.0.113.7" {
+		t.Fatalf("expected source ip without port, got %q", got.SourceIP)
+	}
+}
+
+func TestRequireAdmin(t *testing.T) {
+	handler := requireAdmin(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req = req.WithContext(auth.WithPrincipal(req.Context(), &auth.Principal{KeyID: "key-1", OrganizationID: "org-1"}))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for api key, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req = req.WithContext(auth.WithPrincipal(req.Context(), &auth.Principal{Admin: true}))
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204 for admin, got %d", w.Code)
+	}
+}
+
+func TestAuditEventsInvalidFilter(t *testing.T) {
+	r := testRouter(nil)
+	for _, path := range []string{
+		"/v1/audit-events?since=yesterday",
+		"/v1/audit-events?organization_id=nope",
+		testOrgPath + "/audit-events/export?until=2024-13-01",
+	} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer admin")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", path, w.Code)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/flockiot/flock-api/auth"
+	"github.com/flockiot/flock-api/logging"
+	"github.com/flockiot/flock-api/repository"
+)
+
+func requireOrgAccess(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		orgID := chi.URLParam(r, "orgID")
+		if !repository.IsUUID(orgID) {
+			writeError(w, http.StatusNotFound, "organization not found")
+			return
+		}
+		p, ok := auth.FromContext(r.Context())
+		if !ok || !p.CanAccess(orgID) {
+			writeError(w, http.StatusForbidden, "forbidden")
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(logging.With(r.Context(), "organization_id", orgID)))
+	})
+}
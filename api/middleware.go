@@ -3,24 +3,96 @@ package api
 import (
 	"log/slog"
 	"net/http"
+	"slices"
 	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/flockiot/flock-api/logging"
+	"github.com/flockiot/flock-api/metrics"
+	"github.com/flockiot/flock-api/tracing"
 )
 
-func requestLogger(next http.Handler) http.Handler {
+const requestIDHeader = "X-Request-ID"
+
+// The request ID is echoed so clients can quote it in bug reports.
+func requestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := logging.RequestIDOrNew(r.Header.Get(requestIDHeader))
+		w.Header().Set(requestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(logging.WithRequestID(r.Context(), id)))
+	})
+}
+
+// skip keeps probes from dominating the access log.
+func requestLogger(skip []string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			ww := &responseWriter{ResponseWriter: w, status: http.StatusOK}
+
+			next.ServeHTTP(ww, r)
+
+			if rctx := chi.RouteContext(r.Context()); rctx != nil && slices.Contains(skip, rctx.RoutePattern()) {
+				return
+			}
+			slog.InfoContext(r.Context(), "http request",
+				"method", r.Method,
+				"path", r.URL.Path,
+				"status", ww.status,
+				"duration_ms", time.Since(start).Milliseconds(),
+				"remote_ip", r.RemoteAddr,
+				"bytes", ww.bytes,
+			)
+		})
+	}
+}
+
+// The span is renamed to the route pattern once routing has finished.
+func requestTracing(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracing.Tracer().Start(ctx, r.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(r.Method),
+				semconv.URLPath(r.URL.Path),
+			),
+		)
+		defer span.End()
+		ww := &responseWriter{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(ww, r.WithContext(ctx))
+
+		if rctx := chi.RouteContext(ctx); rctx != nil && rctx.RoutePattern() != "" {
+			span.SetName(r.Method + " " + rctx.RoutePattern())
+			span.SetAttributes(semconv.HTTPRoute(rctx.RoutePattern()))
+		}
+		span.SetAttributes(semconv.HTTPResponseStatusCode(ww.status))
+		if ww.status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(ww.status))
+		}
+	})
+}
+
+// The route pattern is only complete once routing has finished.
+func requestMetrics(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 		ww := &responseWriter{ResponseWriter: w, status: http.StatusOK}
 
 		next.ServeHTTP(ww, r)
 
-		slog.Info("http request",
-			"method", r.Method,
-			"path", r.URL.Path,
-			"status", ww.status,
-			"duration_ms", time.Since(start).Milliseconds(),
-			"remote_ip", r.RemoteAddr,
-			"bytes", ww.bytes,
-		)
+		route := "unmatched"
+		if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+			route = rctx.RoutePattern()
+		}
+		metrics.ObserveHTTPRequest(r.Method, route, ww.status, time.Since(start))
 	})
 }
 
@@ -7,13 +7,24 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/flockiot/flock-api/config"
+	"github.com/flockiot/flock-api/logging"
+	"github.com/flockiot/flock-api/metrics"
 )
 
 func TestRequestLoggerLogsFields(t *testing.T) {
 	var buf bytes.Buffer
 	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
 
-	handler := requestLogger(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+	handler := requestLogger(nil)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		_, _ = w.Write([]byte("hello"))
 	}))
@@ -49,3 +60,106 @@ func TestResponseWriterCapturesBytes(t *testing.T) {
 		t.Fatalf("expected 5 bytes, got %d", ww.bytes)
 	}
 }
+
+func TestRequestMetricsUseRoutePattern(t *testing.T) {
+	r := testRouter(nil)
+	req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	req = httptest.NewRequest(http.MethodGet, "/no/such/path", nil)
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	w := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	out := w.Body.String()
+	for _, want := range []string{
+		`flock_http_requests_total{code="503",method="GET",route="/readyz"}`,
+		`flock_http_requests_total{code="404",method="GET",route="unmatched"}`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("metrics output missing %s", want)
+		}
+	}
+	if strings.Contains(out, "/no/such/path") {
+		t.Error("raw path should not be used as a label")
+	}
+}
+
+func TestRequestTracingContinuesTrace(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTracerProvider(noop.NewTracerProvider()) })
+
+	r := testRouter(nil)
+	req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "GET /readyz" {
+		t.Fatalf("expected span named after route, got %q", span.Name())
+	}
+	if span.SpanContext().TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Fatalf("expected trace ID from traceparent, got %s", span.SpanContext().TraceID())
+	}
+	if span.Parent().SpanID().String() != "00f067aa0ba902b7" {
+		t.Fatalf("expected remote parent, got %s", span.Parent().SpanID())
+	}
+	if span.Status().Code != codes.Error {
+		t.Fatalf("expected error status for 503, got %v", span.Status().Code)
+	}
+}
+
+func TestRequestIDHeader(t *testing.T) {
+	r := testRouter(nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/livez", nil)
+	req.Header.Set("X-Request-ID", "client-id-1")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if got := w.Header().Get("X-Request-ID"); got != "client-id-1" {
+		t.Fatalf("expected caller's request ID echoed, got %q", got)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/livez", nil)
+	req.Header.Set("X-Request-ID", "bad id\n")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if got := w.Header().Get("X-Request-ID"); got == "" || got == "bad id\n" {
+		t.Fatalf("expected a generated request ID, got %q", got)
+	}
+}
+
+func TestRequestLoggerIncludesContext(t *testing.T) {
+	var buf bytes.Buffer
+	slog.SetDefault(slog.New(logging.ContextHandler(slog.NewJSONHandler(&buf, nil))))
+
+	r := testRouter(nil)
+	req := httptest.NewRequest(http.MethodGet, "/livez", nil)
+	req.Header.Set("X-Request-ID", "client-id-2")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	if !strings.Contains(buf.String(), `"request_id":"client-id-2"`) {
+		t.Fatalf("expected request_id in access log, got: %s", buf.String())
+	}
+}
+
+func TestRequestLoggerSkipsRoutes(t *testing.T) {
+	var buf bytes.Buffer
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+
+	r := NewRouter(&config.Config{Log: config.LogConfig{AccessSkip: []string{"/livez"}}}, nil, nil, nil, testChecks(nil))
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/livez", nil))
+	if buf.Len() != 0 {
+		t.Fatalf("expected no access log for /livez, got: %s", buf.String())
+	}
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	if !strings.Contains(buf.String(), `"path":"/openapi.json"`) {
+		t.Fatalf("expected access log for /openapi.json, got: %s", buf.String())
+	}
+}
@@ -0,0 +1,516 @@
+package api
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/flockiot/flock-api/repository"
+)
+
+//go:embed openapi.json
+var openAPIDocument []byte
+
+var openAPI = mustParseOpenAPI(openAPIDocument)
+
+// openAPISpec is the subset of OpenAPI 3.1 that request validation needs.
+type openAPISpec struct {
+	Paths      map[string]*openAPIPathItem `json:"paths"`
+	Components struct {
+		Parameters map[string]*openAPIParameter `json:"parameters"`
+		Schemas    map[string]*jsonSchema       `json:"schemas"`
+	} `json:"components"`
+
+	routes []specRoute
+}
+
+type openAPIPathItem struct {
+	Parameters []*openAPIParameter
+	Operations map[string]*openAPIOperation
+}
+
+func (p *openAPIPathItem) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	p.Operations = map[string]*openAPIOperation{}
+	for key, v := range raw {
+		switch key {
+		case "parameters":
+			if err := json.Unmarshal(v, &p.Parameters); err != nil {
+				return err
+			}
+		case "get", "put", "post", "delete", "options", "head", "patch", "trace":
+			op := &openAPIOperation{}
+			if err := json.Unmarshal(v, op); err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+			p.Operations[strings.ToUpper(key)] = op
+		}
+	}
+	return nil
+}
+
+type openAPIOperation struct {
+	Parameters  []*openAPIParameter `json:"parameters"`
+	RequestBody *openAPIRequestBody `json:"requestBody"`
+}
+
+type openAPIParameter struct {
+	Ref      string      `json:"$ref"`
+	Name     string      `json:"name"`
+	In       string      `json:"in"`
+	Required bool        `json:"required"`
+	Schema   *jsonSchema `json:"schema"`
+}
+
+type openAPIRequestBody struct {
+	Required bool `json:"required"`
+	Content  map[string]struct {
+		Schema *jsonSchema `json:"schema"`
+	} `json:"content"`
+}
+
+// Unknown JSON Schema keywords are ignored rather than rejected.
+type jsonSchema struct {
+	Ref                  string                 `json:"$ref"`
+	Type                 schemaTypes            `json:"type"`
+	Format               string                 `json:"format"`
+	Enum                 []any                  `json:"enum"`
+	Properties           map[string]*jsonSchema `json:"properties"`
+	Required             []string               `json:"required"`
+	AdditionalProperties *bool                  `json:"additionalProperties"`
+	Items                *jsonSchema            `json:"items"`
+	MinLength            *int                   `json:"minLength"`
+	MaxLength            *int                   `json:"maxLength"`
+	Minimum              *float64               `json:"minimum"`
+	Maximum              *float64               `json:"maximum"`
+}
+
+// schemaTypes also accepts a list, which OpenAPI 3.1 uses for nullable values.
+type schemaTypes []string
+
+func (t *schemaTypes) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*t = schemaTypes{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*t = many
+	return nil
+}
+
+type specRoute struct {
+	segments []string
+	item     *openAPIPathItem
+}
+
+func mustParseOpenAPI(data []byte) *openAPISpec {
+	spec, err := parseOpenAPI(data)
+	if err != nil {
+		panic(fmt.Sprintf("parsing openapi.json: %v", err))
+	}
+	return spec
+}
+
+func parseOpenAPI(data []byte) (*openAPISpec, error) {
+	spec := &openAPISpec{}
+	if err := json.Unmarshal(data, spec); err != nil {
+		return nil, err
+	}
+	for path, item := range spec.Paths {
+		for i, p := range item.Parameters {
+			resolved, err := spec.resolveParameter(p)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			item.Parameters[i] = resolved
+		}
+		for method, op := range item.Operations {
+			for i, p := range op.Parameters {
+				resolved, err := spec.resolveParameter(p)
+				if err != nil {
+					return nil, fmt.Errorf("%s %s: %w", method, path, err)
+				}
+				op.Parameters[i] = resolved
+			}
+		}
+		spec.routes = append(spec.routes, specRoute{segments: splitPath(path), item: item})
+	}
+	// Literal segments take precedence over templates, as they do in the
+	// router.
+	slices.SortFunc(spec.routes, func(a, b specRoute) int {
+		return strings.Compare(strings.Join(a.segments, "/"), strings.Join(b.segments, "/"))
+	})
+	for name, s := range spec.Components.Schemas {
+		if err := spec.resolveSchema(s); err != nil {
+			return nil, fmt.Errorf("schema %s: %w", name, err)
+		}
+	}
+	for path, item := range spec.Paths {
+		for method, op := range item.Operations {
+			if op.RequestBody == nil {
+				continue
+			}
+			for _, media := range op.RequestBody.Content {
+				if err := spec.resolveSchema(media.Schema); err != nil {
+					return nil, fmt.Errorf("%s %s: %w", method, path, err)
+				}
+			}
+		}
+	}
+	return spec, nil
+}
+
+func (s *openAPISpec) resolveParameter(p *openAPIParameter) (*openAPIParameter, error) {
+	if p.Ref == "" {
+		return p, s.resolveSchema(p.Schema)
+	}
+	name, ok := strings.CutPrefix(p.Ref, "#/components/parameters/")
+	target := s.Components.Parameters[name]
+	if !ok || target == nil {
+		return nil, fmt.Errorf("unresolved reference %q", p.Ref)
+	}
+	return target, s.resolveSchema(target.Schema)
+}
+
+func (s *openAPISpec) resolveSchema(sc *jsonSchema) error {
+	if sc == nil {
+		return nil
+	}
+	if sc.Ref != "" {
+		name, ok := strings.CutPrefix(sc.Ref, "#/components/schemas/")
+		target := s.Components.Schemas[name]
+		if !ok || target == nil {
+			return fmt.Errorf("unresolved reference %q", sc.Ref)
+		}
+		*sc = *target
+		return s.resolveSchema(sc)
+	}
+	for _, p := range sc.Properties {
+		if err := s.resolveSchema(p); err != nil {
+			return err
+		}
+	}
+	return s.resolveSchema(sc.Items)
+}
+
+func splitPath(path string) []string {
+	return strings.Split(strings.Trim(path, "/"), "/")
+}
+
+// find ignores trailing slashes, as the router does.
+func (s *openAPISpec) find(path string) *openAPIPathItem {
+	segments := splitPath(path)
+	for _, route := range s.routes {
+		if len(route.segments) != len(segments) {
+			continue
+		}
+		match := true
+		for i, seg := range route.segments {
+			if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+				match = segments[i] != ""
+			} else {
+				match = seg == segments[i]
+			}
+			if !match {
+				break
+			}
+		}
+		if match {
+			return route.item
+		}
+	}
+	return nil
+}
+
+func handleOpenAPI(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(openAPIDocument)
+}
+
+// redocScript is pinned so a new release cannot change or break the page.
+const redocScript = "https://cdn.jsdelivr.net/npm/redoc@2.1.5/bundles/redoc.standalone.js"
+
+// Redoc renders with inline styles and web workers created from blobs.
+const docsCSP = "default-src 'none'; script-src " + redocScript + "; worker-src blob:; " +
+	"style-src 'unsafe-inline'; img-src data: https:; font-src data:; connect-src 'self'"
+
+const docsPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Flock API</title>
+</head>
+<body>
+<redoc spec-url="/openapi.json"></redoc>
+<script src="` + redocScript + `" crossorigin="anonymous"></script>
+</body>
+</html>
+`
+
+func handleDocs(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", docsCSP)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte(docsPage))
+}
+
+// validateRequests leaves path parameters to the handlers, which report
+// malformed IDs as 404 like any other unknown ID.
+func validateRequests(spec *openAPISpec) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			item := spec.find(r.URL.Path)
+			if item == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			op := item.Operations[r.Method]
+			if op == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if err := validateQuery(r.URL.Query(), item.Parameters, op.Parameters); err != nil {
+				writeError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			if op.RequestBody != nil {
+				status, err := validateBody(r, op.RequestBody)
+				if err != nil {
+					writeError(w, status, err.Error())
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func validateQuery(q url.Values, pathParams, opParams []*openAPIParameter) error {
+	for _, p := range slices.Concat(pathParams, opParams) {
+		if p.In != "query" {
+			continue
+		}
+		v, ok := q[p.Name]
+		if !ok || v[0] == "" {
+			if p.Required {
+				return fmt.Errorf("%s is required", p.Name)
+			}
+			continue
+		}
+		if err := validateParameterValue(p.Schema, v[0]); err != nil {
+			return fmt.Errorf("%s %w", p.Name, err)
+		}
+	}
+	return nil
+}
+
+func validateParameterValue(sc *jsonSchema, raw string) error {
+	if sc == nil {
+		return nil
+	}
+	var v any = raw
+	switch {
+	case slices.Contains(sc.Type, "integer"):
+		if _, err := strconv.ParseInt(raw, 10, 64); err != nil {
+			return errors.New("must be an integer")
+		}
+		v = json.Number(raw)
+	case slices.Contains(sc.Type, "number"):
+		if _, err := strconv.ParseFloat(raw, 64); err != nil {
+			return errors.New("must be a number")
+		}
+		v = json.Number(raw)
+	case slices.Contains(sc.Type, "boolean"):
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return errors.New("must be a boolean")
+		}
+		v = b
+	}
+	return validateSchema(sc, v)
+}
+
+func validateBody(r *http.Request, body *openAPIRequestBody) (int, error) {
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		mediaType, _, err := mime.ParseMediaType(ct)
+		if err != nil || mediaType != "application/json" {
+			return http.StatusUnsupportedMediaType, errors.New("content type must be application/json")
+		}
+	}
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxBodyBytes))
+	if err != nil {
+		return http.StatusBadRequest, fmt.Errorf("reading request body: %w", err)
+	}
+	r.Body = io.NopCloser(bytes.NewReader(data))
+
+	if len(bytes.TrimSpace(data)) == 0 {
+		if body.Required {
+			return http.StatusBadRequest, errors.New("request body is empty")
+		}
+		return 0, nil
+	}
+
+	var v any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err)
+	}
+	media, ok := body.Content["application/json"]
+	if !ok {
+		return 0, nil
+	}
+	if err := validateSchema(media.Schema, v); err != nil {
+		return http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err)
+	}
+	return 0, nil
+}
+
+type schemaError struct {
+	path string
+	msg  string
+}
+
+func (e *schemaError) Error() string {
+	if e.path == "" {
+		return e.msg
+	}
+	return e.path + " " + e.msg
+}
+
+func validateSchema(sc *jsonSchema, v any) error {
+	return validateSchemaAt(sc, v, "")
+}
+
+func validateSchemaAt(sc *jsonSchema, v any, path string) error {
+	if sc == nil {
+		return nil
+	}
+	fail := func(format string, args ...any) error {
+		return &schemaError{path: path, msg: fmt.Sprintf(format, args...)}
+	}
+
+	if len(sc.Type) > 0 && !slices.ContainsFunc(sc.Type, func(t string) bool { return hasJSONType(v, t) }) {
+		return fail("must be of type %s", strings.Join(sc.Type, " or "))
+	}
+	if len(sc.Enum) > 0 && !slices.ContainsFunc(sc.Enum, func(e any) bool { return fmt.Sprint(e) == fmt.Sprint(v) }) {
+		return fail("must be one of %v", sc.Enum)
+	}
+
+	switch v := v.(type) {
+	case string:
+		n := utf8.RuneCountInString(v)
+		if sc.MinLength != nil && n < *sc.MinLength {
+			return fail("must be at least %d characters", *sc.MinLength)
+		}
+		if sc.MaxLength != nil && n > *sc.MaxLength {
+			return fail("must be at most %d characters", *sc.MaxLength)
+		}
+		if err := validateFormat(sc.Format, v); err != nil {
+			return fail("%s", err)
+		}
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return fail("must be a number")
+		}
+		if sc.Minimum != nil && f < *sc.Minimum {
+			return fail("must be at least %v", *sc.Minimum)
+		}
+		if sc.Maximum != nil && f > *sc.Maximum {
+			return fail("must be at most %v", *sc.Maximum)
+		}
+	case []any:
+		for i, item := range v {
+			if err := validateSchemaAt(sc.Items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case map[string]any:
+		for _, name := range sc.Required {
+			if _, ok := v[name]; !ok {
+				return &schemaError{path: joinPath(path, name), msg: "is required"}
+			}
+		}
+		for name, value := range v {
+			prop, ok := sc.Properties[name]
+			if !ok {
+				if sc.AdditionalProperties != nil && !*sc.AdditionalProperties {
+					return &schemaError{path: joinPath(path, name), msg: "is not allowed"}
+				}
+				continue
+			}
+			if err := validateSchemaAt(prop, value, joinPath(path, name)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func hasJSONType(v any, t string) bool {
+	switch v := v.(type) {
+	case nil:
+		return t == "null"
+	case bool:
+		return t == "boolean"
+	case string:
+		return t == "string"
+	case json.Number:
+		if t == "number" {
+			return true
+		}
+		_, err := v.Int64()
+		return t == "integer" && err == nil
+	case []any:
+		return t == "array"
+	case map[string]any:
+		return t == "object"
+	}
+	return false
+}
+
+func validateFormat(format, v string) error {
+	switch format {
+	case "uuid":
+		if !repository.IsUUID(v) {
+			return errors.New("must be a UUID")
+		}
+	case "date-time":
+		if _, err := time.Parse(time.RFC3339, v); err != nil {
+			return errors.New("must be an RFC 3339 timestamp")
+		}
+	case "uri":
+		if u, err := url.Parse(v); err != nil || u.Scheme == "" {
+			return errors.New("must be an absolute URI")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/flockiot/flock-api/config"
+	"github.com/flockiot/flock-api/health"
+)
+
+func specPath(route string) string {
+	if route != "/" {
+		route = strings.TrimSuffix(route, "/")
+	}
+	return route
+}
+
+func TestOpenAPICoversRoutes(t *testing.T) {
+	r := NewRouter(&config.Config{}, nil, nil, nil, health.NewRegistry())
+
+	routed := map[string]bool{}
+	err := chi.Walk(r, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		path := specPath(route)
+		routed[method+" "+path] = true
+
+		item, ok := openAPI.Paths[path]
+		if !ok {
+			t.Errorf("%s %s is not described in openapi.json", method, path)
+			return nil
+		}
+		if _, ok := item.Operations[method]; !ok {
+			t.Errorf("%s %s has no operation in openapi.json", method, path)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for path, item := range openAPI.Paths {
+		for method := range item.Operations {
+			if !routed[method+" "+path] {
+				t.Errorf("openapi.json describes %s %s but no route serves it", method, path)
+			}
+		}
+	}
+}
+
+func TestOpenAPIEndpoint(t *testing.T) {
+	r := testRouter(nil)
+	req := httptest.NewRequest(http.MethodGet, "/openapi.json", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var doc struct {
+		OpenAPI string `json:"openapi"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&doc); err != nil {
+		t.Fatalf("failed to decode: %v", err)
+	}
+	if doc.OpenAPI != "3.1.0" {
+		t.Fatalf("expected openapi 3.1.0, got %q", doc.OpenAPI)
+	}
+}
+
+func TestDocsPagePinsRedoc(t *testing.T) {
+	w := httptest.NewRecorder()
+	testRouter(nil).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/docs", nil))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), `src="`+redocScript+`"`) || strings.Contains(redocScript, "latest") {
+		t.Fatalf("expected the page to load a pinned redoc release:\n%s", w.Body.String())
+	}
+
+	csp := map[string]string{}
+	for _, directive := range strings.Split(w.Header().Get("Content-Security-Policy"), ";") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), " ")
+		csp[name] = value
+	}
+	for name, want := range map[string]string{
+		"default-src": "'none'",
+		"script-src":  redocScript,
+		"connect-src": "'self'",
+	} {
+		if got := csp[name]; got != want {
+			t.Errorf("Content-Security-Policy %s = %q, want %q", name, got, want)
+		}
+	}
+	if strings.Count(w.Body.String(), "<script") != 1 {
+		t.Errorf("expected no script besides redoc, since the policy blocks any other")
+	}
+	if !strings.Contains(w.Body.String(), `spec-url="/openapi.json"`) {
+		t.Errorf("expected the spec to be fetched from the same origin, since connect-src allows only that")
+	}
+}
+
+func TestValidateRequests(t *testing.T) {
+	r := testRouter(nil)
+	cases := []struct {
+		name        string
+		method      string
+		path        string
+		contentType string
+		body        string
+		status      int
+		message     string
+	}{
+		{"limit below minimum", http.MethodGet, "/v1/organizations?limit=0", "", "", http.StatusBadRequest, "limit must be at least 1"},
+		{"limit not integer", http.MethodGet, "/v1/organizations?limit=x", "", "", http.StatusBadRequest, "limit must be an integer"},
+		{"bad timestamp", http.MethodGet, "/v1/audit-events?since=yesterday", "", "", http.StatusBadRequest, "since must be an RFC 3339 timestamp"},
+		{"missing field", http.MethodPost, "/v1/organizations", "", `{}`, http.StatusBadRequest, "invalid request body: name is required"},
+		{"wrong type", http.MethodPost, "/v1/organizations", "", `{"name":7}`, http.StatusBadRequest, "invalid request body: name must be of type string"},
+		{"too long", http.MethodPost, "/v1/organizations", "", `{"name":"` + strings.Repeat("a", 101) + `"}`, http.StatusBadRequest, "invalid request body: name must be at most 100 characters"},
+		{"unknown field", http.MethodPost, "/v1/organizations", "", `{"name":"a","extra":1}`, http.StatusBadRequest, "invalid request body: extra is not allowed"},
+		{"nested array item", http.MethodPost, testOrgPath + "/webhooks", "", `{"url":"https://example.com","event_types":[1]}`, http.StatusBadRequest, "invalid request body: event_types[0] must be of type string"},
+		{"wrong content type", http.MethodPost, "/v1/organizations", "text/plain", `{"name":"a"}`, http.StatusUnsupportedMediaType, "content type must be application/json"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+			req.Header.Set("Authorization", "Bearer admin")
+			if tc.contentType != "" {
+				req.Header.Set("Content-Type", tc.contentType)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tc.status {
+				t.Fatalf("expected %d, got %d: %s", tc.status, w.Code, w.Body.String())
+			}
+			var resp errorResponse
+			if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode: %v", err)
+			}
+			if resp.Error != tc.message {
+				t.Fatalf("expected error %q, got %q", tc.message, resp.Error)
+			}
+		})
+	}
+}
+
+func TestValidateRequestsPreservesBody(t *testing.T) {
+	var got organizationRequest
+	handler := validateRequests(openAPI)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := decodeJSON(r, &got); err != nil {
+			t.Fatalf("handler could not decode body: %v", err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/organizations", strings.NewReader(`{"name":"acme"}`))
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d: %s", w.Code, w.Body.String())
+	}
+	if got.Name != "acme" {
+		t.Fatalf("expected name acme, got %q", got.Name)
+	}
+}
+
+func TestSchemaTypesAcceptsList(t *testing.T) {
+	var sc jsonSchema
+	if err := json.Unmarshal([]byte(`{"type":["string","null"]}`), &sc); err != nil {
+		t.Fatal(err)
+	}
+	if err := validateSchema(&sc, nil); err != nil {
+		t.Fatalf("null should be accepted: %v", err)
+	}
+	if err := validateSchema(&sc, true); err == nil {
+		t.Fatal("boolean should be rejected")
+	}
+}
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/flockiot/flock-api/repository"
+)
+
+type organizationResponse struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func newOrganizationResponse(o *repository.Organization) organizationResponse {
+	return organizationResponse{
+		ID:        o.ID,
+		Name:      o.Name,
+		CreatedAt: o.CreatedAt,
+		UpdatedAt: o.UpdatedAt,
+	}
+}
+
+type organizationRequest struct {
+	Name string `json:"name"`
+}
+
+func handleListOrganizations(repo *repository.OrganizationRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		limit, offset, err := parsePagination(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		orgs, err := repo.List(r.Context(), limit, offset)
+		if err != nil {
+			writeRepositoryError(w, r, err, "listing organizations")
+			return
+		}
+		resp := make([]organizationResponse, 0, len(orgs))
+		for _, o := range orgs {
+			resp = append(resp, newOrganizationResponse(o))
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"organizations": resp})
+	}
+}
+
+func handleCreateOrganization(repo *repository.OrganizationRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req organizationRequest
+		if err := decodeJSON(r, &req); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		org, err := repo.Create(r.Context(), req.Name)
+		if err != nil {
+			writeRepositoryError(w, r, err, "creating organization")
+			return
+		}
+		writeJSON(w, http.StatusCreated, newOrganizationResponse(org))
+	}
+}
+
+func handleGetOrganization(repo *repository.OrganizationRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		org, err := repo.GetByID(r.Context(), chi.URLParam(r, "orgID"))
+		if err != nil {
+			writeRepositoryError(w, r, err, "getting organization")
+			return
+		}
+		if org == nil {
+			writeError(w, http.StatusNotFound, "organization not found")
+			return
+		}
+		writeJSON(w, http.StatusOK, newOrganizationResponse(org))
+	}
+}
+
+func handleUpdateOrganization(repo *repository.OrganizationRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req organizationRequest
+		if err := decodeJSON(r, &req); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		org, err := repo.Update(r.Context(), chi.URLParam(r, "orgID"), req.Name)
+		if err != nil {
+			writeRepositoryError(w, r, err, "updating organization")
+			return
+		}
+		if org == nil {
+			writeError(w, http.StatusNotFound, "organization not found")
+			return
+		}
+		writeJSON(w, http.StatusOK, newOrganizationResponse(org))
+	}
+}
+
+func handleDeleteOrganization(repo *repository.OrganizationRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := repo.Delete(r.Context(), chi.URLParam(r, "orgID")); err != nil {
+			writeRepositoryError(w, r, err, "deleting organization")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
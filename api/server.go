@@ -8,88 +8,247 @@ import (
 	"log/slog"
 	"net"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/jackc/pgx/v5/pgxpool"
 
+	"github.com/flockiot/flock-api/auth"
+	"github.com/flockiot/flock-api/certs"
 	"github.com/flockiot/flock-api/config"
+	"github.com/flockiot/flock-api/database"
+	"github.com/flockiot/flock-api/health"
+	"github.com/flockiot/flock-api/ratelimit"
+	"github.com/flockiot/flock-api/repository"
 	"github.com/flockiot/flock-api/version"
 )
 
-func Start(ctx context.Context, cfg *config.Config, pool *pgxpool.Pool) error {
-	r := NewRouter(pool)
+const (
+	rateLimitPruneInterval = 10 * time.Minute
+	rateLimitMinIdle       = time.Hour
+)
+
+func Start(ctx context.Context, cfg *config.Config, pool *pgxpool.Pool, replicas *database.Replicas, limiter *ratelimit.Limiter, checks *health.Registry) error {
+	r := NewRouter(cfg, pool, replicas, limiter, checks)
+	tlsFiles, err := certs.New(cfg.Server)
+	if err != nil {
+		return err
+	}
 
 	addr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port)
 	srv := &http.Server{
 		Addr:    addr,
 		Handler: r,
+		// In-flight requests are left to finish during shutdown.
 		BaseContext: func(_ net.Listener) context.Context {
-			return ctx
+			return context.WithoutCancel(ctx)
 		},
 	}
 
+	var listener health.Listener
+	checks.Register("api/http", listener.Check)
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	listener.Serving()
+	defer listener.Stopped()
+
+	slog.Info("api server listening", "addr", addr, "tls", tlsFiles != nil)
+	errCh := make(chan error, 1)
 	go func() {
-		<-ctx.Done()
-		slog.Info("api server shutting down")
-		if err := srv.Shutdown(context.Background()); err != nil {
-			slog.Error("server shutdown error", "error", err)
+		if tlsFiles != nil {
+			srv.TLSConfig = tlsFiles.TLSConfig("h2", "http/1.1")
+			errCh <- srv.ServeTLS(lis, "", "")
+			return
 		}
+		errCh <- srv.Serve(lis)
 	}()
-
-	slog.Info("api server listening", "addr", addr)
-	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+	select {
+	case err := <-errCh:
 		return err
+	case <-ctx.Done():
+	}
+
+	slog.Info("api server shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Supervisor.ServerShutdownTimeout())
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		slog.Error("api server shutdown timed out, closing connections", "error", err)
+		return srv.Close()
 	}
 	return nil
 }
 
-func NewRouter(pool *pgxpool.Pool) chi.Router {
+// A nil limiter disables rate limiting.
+func NewRouter(cfg *config.Config, pool *pgxpool.Pool, replicas *database.Replicas, limiter *ratelimit.Limiter, checks *health.Registry) chi.Router {
 	r := chi.NewRouter()
 
 	r.Use(middleware.Recoverer)
+	r.Use(requestID)
 	r.Use(middleware.RealIP)
-	r.Use(requestLogger)
+	r.Use(auth.ClientCertificate)
+	r.Use(requestTracing)
+	r.Use(requestLogger(cfg.Log.AccessSkip))
+	r.Use(requestMetrics)
 
 	r.Get("/livez", handleLivez)
-	r.Get("/readyz", handleReadyz(pool))
+	r.Get("/readyz", handleReadyz(checks))
+	r.Get("/openapi.json", handleOpenAPI)
+	r.Get("/docs", handleDocs)
+
+	authenticator := auth.NewAuthenticator(cfg.Auth.AdminToken, nil)
+	if pool != nil {
+		authenticator = auth.NewAuthenticator(cfg.Auth.AdminToken, repository.NewAPIKeyRepository(pool))
+	}
+
+	orgRepo := repository.NewOrganizationRepository(pool, replicas)
+	auditRepo := repository.NewAuditRepository(pool, replicas)
+
+	r.Route("/v1", func(r chi.Router) {
+		r.Use(authenticator.Middleware)
+		if limiter != nil {
+			r.Use(limiter.Middleware)
+		}
+		r.Use(auditContext)
+		r.Use(validateRequests(openAPI))
+		r.With(requireAdmin).Route("/audit-events", auditRoutes(auditRepo))
+		r.With(requireAdmin).Get("/organizations", handleListOrganizations(orgRepo))
+		r.With(requireAdmin).Post("/organizations", handleCreateOrganization(orgRepo))
+		r.Route("/organizations/{orgID}", func(r chi.Router) {
+			r.Use(requireOrgAccess)
+			r.Get("/", handleGetOrganization(orgRepo))
+			r.Patch("/", handleUpdateOrganization(orgRepo))
+			r.With(requireAdmin).Delete("/", handleDeleteOrganization(orgRepo))
+			r.Route("/webhooks", webhookRoutes(repository.NewWebhookRepository(pool, replicas)))
+			r.Route("/audit-events", auditRoutes(auditRepo))
+		})
+	})
 
 	return r
 }
 
+// NewLimiter returns nil when rate limiting is disabled. Without a database
+// the memory store is used regardless of configuration.
+func NewLimiter(ctx context.Context, cfg *config.Config, pool *pgxpool.Pool) (*ratelimit.Limiter, error) {
+	if !cfg.RateLimit.Enabled {
+		return nil, nil
+	}
+	var store ratelimit.Store
+	switch {
+	case cfg.RateLimit.Store == "memory" || pool == nil:
+		store = ratelimit.NewMemoryStore()
+	case cfg.RateLimit.Store == "postgres":
+		store = ratelimit.NewPostgresStore(repository.NewRateLimitRepository(pool))
+	default:
+		return nil, fmt.Errorf("unknown rate limit store %q (valid: memory, postgres)", cfg.RateLimit.Store)
+	}
+	limiter, err := ratelimit.New(cfg.RateLimit, store)
+	if err != nil {
+		return nil, fmt.Errorf("configuring rate limits: %w", err)
+	}
+	if _, ok := store.(*ratelimit.PostgresStore); ok {
+		go pruneRateLimits(ctx, repository.NewRateLimitRepository(pool), rateLimitIdle(cfg.RateLimit))
+	}
+	config.Subscribe(ctx, "rate_limit.", func(cfg *config.Config) {
+		if err := limiter.Update(cfg.RateLimit); err != nil {
+			slog.Error("updating rate limits failed", "error", err)
+		}
+	})
+	return limiter, nil
+}
+
+// Deleting a bucket before it has refilled would hand the client extra tokens.
+func rateLimitIdle(cfg config.RateLimitConfig) time.Duration {
+	idle := rateLimitMinIdle
+	for _, spec := range cfg.Tiers {
+		if limit, err := ratelimit.ParseLimit(spec); err == nil {
+			idle = max(idle, limit.RefillTime())
+		}
+	}
+	return idle
+}
+
+func pruneRateLimits(ctx context.Context, repo *repository.RateLimitRepository, idle time.Duration) {
+	ticker := time.NewTicker(rateLimitPruneInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := repo.DeleteIdle(ctx, time.Now().Add(-idle))
+			if err != nil {
+				slog.Error("pruning rate limit buckets failed", "error", err)
+				continue
+			}
+			slog.Debug("pruned rate limit buckets", "deleted", n)
+		}
+	}
+}
+
 func handleLivez(w http.ResponseWriter, _ *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	_, _ = w.Write([]byte("ok"))
 }
 
-func handleReadyz(pool *pgxpool.Pool) http.HandlerFunc {
+func handleReadyz(checks *health.Registry) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		resp := readyzResponse{Version: version.Get()}
-
-		if pool == nil {
-			resp.Status = "database not configured"
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusServiceUnavailable)
-			_ = json.NewEncoder(w).Encode(resp)
-			return
+		q := r.URL.Query()
+		var exclude []string
+		for _, v := range q["exclude"] {
+			exclude = append(exclude, strings.Split(v, ",")...)
 		}
-		if err := pool.Ping(r.Context()); err != nil {
-			slog.Error("readyz check failed", "error", err)
-			resp.Status = "database unreachable"
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusServiceUnavailable)
-			_ = json.NewEncoder(w).Encode(resp)
-			return
+		verbose := q.Has("verbose")
+
+		results, ok := checks.Run(r.Context(), exclude...)
+		resp := readyzResponse{Status: "ok", Version: version.Get()}
+		status := http.StatusOK
+		if !ok {
+			resp.Status = "unavailable"
+			status = http.StatusServiceUnavailable
+		}
+		for _, res := range results {
+			if res.OK() && !verbose {
+				continue
+			}
+			c := readyzCheck{Name: res.Name, Status: "ok"}
+			if !res.OK() {
+				slog.ErrorContext(r.Context(), "readiness check failed", "check", res.Name, "error", res.Error)
+				c.Status = "failed"
+				c.Error = checkMessage(res.Error)
+			}
+			if verbose {
+				c.DurationMS = float64(res.Duration.Microseconds()) / 1000
+			}
+			resp.Checks = append(resp.Checks, c)
 		}
 
-		resp.Status = "ok"
 		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
+		w.WriteHeader(status)
 		_ = json.NewEncoder(w).Encode(resp)
 	}
 }
 
+// checkMessage hides connection details from unauthenticated callers.
+func checkMessage(err error) string {
+	if errors.Is(err, health.ErrDatabaseUnreachable) {
+		return health.ErrDatabaseUnreachable.Error()
+	}
+	return err.Error()
+}
+
 type readyzResponse struct {
-	Status  string `json:"status"`
-	Version string `json:"version"`
+	Status  string        `json:"status"`
+	Version string        `json:"version"`
+	Checks  []readyzCheck `json:"checks,omitempty"`
+}
+
+type readyzCheck struct {
+	Name       string  `json:"name"`
+	Status     string  `json:"status"`
+	Error      string  `json:"error,omitempty"`
+	DurationMS float64 `json:"duration_ms,omitempty"`
 }
@@ -10,11 +10,18 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"github.com/flockiot/flock-api/config"
+	"github.com/flockiot/flock-api/health"
 	"github.com/flockiot/flock-api/version"
 )
 
+func testChecks(pool *pgxpool.Pool) *health.Registry {
+	checks := health.NewRegistry()
+	checks.Register("database", health.Database(pool))
+	return checks
+}
+
 func testRouter(pool *pgxpool.Pool) http.Handler {
-	return NewRouter(pool)
+	return NewRouter(&config.Config{Auth: config.AuthConfig{AdminToken: "admin"}}, pool, nil, nil, testChecks(pool))
 }
 
 func TestLivez(t *testing.T) {
@@ -49,8 +56,45 @@ func TestReadyzWithoutDB(t *testing.T) {
 	if resp.Version != "1.2.3" {
 		t.Fatalf("expected version '1.2.3', got %q", resp.Version)
 	}
-	if resp.Status != "database not configured" {
-		t.Fatalf("expected status 'database not configured', got %q", resp.Status)
+	if resp.Status != "unavailable" {
+		t.Fatalf("expected status 'unavailable', got %q", resp.Status)
+	}
+	if len(resp.Checks) != 1 || resp.Checks[0].Name != "database" || resp.Checks[0].Error != "database not configured" {
+		t.Fatalf("expected the database check to be reported, got %+v", resp.Checks)
+	}
+}
+
+func TestReadyzBreakdown(t *testing.T) {
+	checks := testChecks(nil)
+	checks.Register("ingester/buffer", func(context.Context) error { return nil })
+	r := NewRouter(&config.Config{}, nil, nil, nil, checks)
+
+	get := func(path string) (int, readyzResponse) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		var resp readyzResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return w.Code, resp
+	}
+
+	code, resp := get("/readyz?verbose")
+	if code != http.StatusServiceUnavailable || len(resp.Checks) != 2 {
+		t.Fatalf("expected 503 listing both checks, got %d %+v", code, resp.Checks)
+	}
+	if resp.Checks[1].Name != "ingester/buffer" || resp.Checks[1].Status != "ok" {
+		t.Fatalf("expected passing ingester check, got %+v", resp.Checks[1])
+	}
+
+	code, resp = get("/readyz?exclude=database")
+	if code != http.StatusOK || resp.Status != "ok" || len(resp.Checks) != 0 {
+		t.Fatalf("expected 200 with no failures listed, got %d %+v", code, resp)
+	}
+
+	code, resp = get("/readyz?verbose&exclude=database,ingester/buffer")
+	if code != http.StatusOK || len(resp.Checks) != 0 {
+		t.Fatalf("expected every check excluded, got %d %+v", code, resp.Checks)
 	}
 }
 
@@ -88,7 +132,7 @@ func TestStartListensAndShutdown(t *testing.T) {
 
 	errCh := make(chan error, 1)
 	go func() {
-		errCh <- Start(ctx, cfg, nil)
+		errCh <- Start(ctx, cfg, nil, nil, nil, testChecks(nil))
 	}()
 
 	cancel()
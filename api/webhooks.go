@@ -0,0 +1,339 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/flockiot/flock-api/repository"
+	"github.com/flockiot/flock-api/webhook"
+)
+
+type webhookResponse struct {
+	ID                  string     `json:"id"`
+	OrganizationID      string     `json:"organization_id"`
+	URL                 string     `json:"url"`
+	Secret              string     `json:"secret,omitempty"`
+	EventTypes          []string   `json:"event_types"`
+	Enabled             bool       `json:"enabled"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	DisabledAt          *time.Time `json:"disabled_at,omitempty"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+}
+
+func newWebhookResponse(w *repository.WebhookSubscription) webhookResponse {
+	return webhookResponse{
+		ID:                  w.ID,
+		OrganizationID:      w.OrganizationID,
+		URL:                 w.URL,
+		EventTypes:          w.EventTypes,
+		Enabled:             w.Enabled,
+		ConsecutiveFailures: w.ConsecutiveFailures,
+		DisabledAt:          w.DisabledAt,
+		CreatedAt:           w.CreatedAt,
+		UpdatedAt:           w.UpdatedAt,
+	}
+}
+
+type webhookAttemptResponse struct {
+	StatusCode *int      `json:"status_code,omitempty"`
+	Error      *string   `json:"error,omitempty"`
+	DurationMS int       `json:"duration_ms"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type webhookDeliveryResponse struct {
+	ID             string                   `json:"id"`
+	WebhookID      string                   `json:"webhook_id"`
+	EventID        int64                    `json:"event_id"`
+	EventType      string                   `json:"event_type"`
+	Payload        json.RawMessage          `json:"payload"`
+	Status         string                   `json:"status"`
+	Attempts       int                      `json:"attempts"`
+	NextAttemptAt  *time.Time               `json:"next_attempt_at,omitempty"`
+	LastStatusCode *int                     `json:"last_status_code,omitempty"`
+	LastError      *string                  `json:"last_error,omitempty"`
+	History        []webhookAttemptResponse `json:"history,omitempty"`
+	CreatedAt      time.Time                `json:"created_at"`
+	UpdatedAt      time.Time                `json:"updated_at"`
+}
+
+func newWebhookDeliveryResponse(d *repository.WebhookDelivery) webhookDeliveryResponse {
+	resp := webhookDeliveryResponse{
+		ID:             d.ID,
+		WebhookID:      d.SubscriptionID,
+		EventID:        d.EventID,
+		EventType:      d.EventType,
+		Payload:        d.Payload,
+		Status:         d.Status,
+		Attempts:       d.Attempts,
+		LastStatusCode: d.LastStatusCode,
+		LastError:      d.LastError,
+		CreatedAt:      d.CreatedAt,
+		UpdatedAt:      d.UpdatedAt,
+	}
+	if d.Status == repository.DeliveryPending {
+		resp.NextAttemptAt = &d.NextAttemptAt
+	}
+	return resp
+}
+
+type createWebhookRequest struct {
+	URL        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+}
+
+type updateWebhookRequest struct {
+	URL        *string   `json:"url"`
+	EventTypes *[]string `json:"event_types"`
+	Enabled    *bool     `json:"enabled"`
+}
+
+func validateWebhookURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("url must be an absolute http or https URL")
+	}
+	// Names are checked when delivering, once they have been resolved.
+	if ip, err := netip.ParseAddr(u.Hostname()); err == nil {
+		if err := webhook.CheckAddr(ip); err != nil {
+			return errors.New("url must not point to a loopback, private or link-local address")
+		}
+	}
+	return nil
+}
+
+func webhookRoutes(repo *repository.WebhookRepository) func(chi.Router) {
+	return func(r chi.Router) {
+		r.Get("/", handleListWebhooks(repo))
+		r.Post("/", handleCreateWebhook(repo))
+		r.Route("/{webhookID}", func(r chi.Router) {
+			r.Use(requireUUIDParam("webhookID", "webhook not found"))
+			r.Get("/", handleGetWebhook(repo))
+			r.Patch("/", handleUpdateWebhook(repo))
+			r.Delete("/", handleDeleteWebhook(repo))
+			r.Get("/deliveries", handleListWebhookDeliveries(repo))
+			r.Route("/deliveries/{deliveryID}", func(r chi.Router) {
+				r.Use(requireUUIDParam("deliveryID", "delivery not found"))
+				r.Get("/", handleGetWebhookDelivery(repo))
+				r.Post("/redeliver", handleRedeliverWebhook(repo))
+			})
+		})
+	}
+}
+
+func requireUUIDParam(name, notFound string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !repository.IsUUID(chi.URLParam(r, name)) {
+				writeError(w, http.StatusNotFound, notFound)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func handleListWebhooks(repo *repository.WebhookRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		limit, offset, err := parsePagination(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		hooks, err := repo.List(r.Context(), chi.URLParam(r, "orgID"), limit, offset)
+		if err != nil {
+			writeRepositoryError(w, r, err, "listing webhooks")
+			return
+		}
+		resp := make([]webhookResponse, 0, len(hooks))
+		for _, h := range hooks {
+			resp = append(resp, newWebhookResponse(h))
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"webhooks": resp})
+	}
+}
+
+func handleCreateWebhook(repo *repository.WebhookRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req createWebhookRequest
+		if err := decodeJSON(r, &req); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err := validateWebhookURL(req.URL); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		hook, err := repo.Create(r.Context(), chi.URLParam(r, "orgID"), req.URL, req.EventTypes)
+		if err != nil {
+			writeRepositoryError(w, r, err, "creating webhook")
+			return
+		}
+		resp := newWebhookResponse(hook)
+		resp.Secret = hook.Secret
+		writeJSON(w, http.StatusCreated, resp)
+	}
+}
+
+func handleGetWebhook(repo *repository.WebhookRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		hook, err := repo.GetByID(r.Context(), chi.URLParam(r, "orgID"), chi.URLParam(r, "webhookID"))
+		if err != nil {
+			writeRepositoryError(w, r, err, "getting webhook")
+			return
+		}
+		if hook == nil {
+			writeError(w, http.StatusNotFound, "webhook not found")
+			return
+		}
+		writeJSON(w, http.StatusOK, newWebhookResponse(hook))
+	}
+}
+
+func handleUpdateWebhook(repo *repository.WebhookRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req updateWebhookRequest
+		if err := decodeJSON(r, &req); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if req.URL != nil {
+			if err := validateWebhookURL(*req.URL); err != nil {
+				writeError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+		}
+
+		hook, err := repo.Update(r.Context(), chi.URLParam(r, "orgID"), chi.URLParam(r, "webhookID"), repository.WebhookUpdate{
+			URL:        req.URL,
+			EventTypes: req.EventTypes,
+			Enabled:    req.Enabled,
+		})
+		if err != nil {
+			writeRepositoryError(w, r, err, "updating webhook")
+			return
+		}
+		if hook == nil {
+			writeError(w, http.StatusNotFound, "webhook not found")
+			return
+		}
+		writeJSON(w, http.StatusOK, newWebhookResponse(hook))
+	}
+}
+
+func handleDeleteWebhook(repo *repository.WebhookRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		err := repo.Delete(r.Context(), chi.URLParam(r, "orgID"), chi.URLParam(r, "webhookID"))
+		if err != nil {
+			writeRepositoryError(w, r, err, "deleting webhook")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// lookupWebhook reads from the primary, as its callers act on the webhook.
+func lookupWebhook(w http.ResponseWriter, r *http.Request, repo *repository.WebhookRepository) *repository.WebhookSubscription {
+	hook, err := repo.GetByIDPrimary(r.Context(), chi.URLParam(r, "orgID"), chi.URLParam(r, "webhookID"))
+	if err != nil {
+		writeRepositoryError(w, r, err, "getting webhook")
+		return nil
+	}
+	if hook == nil {
+		writeError(w, http.StatusNotFound, "webhook not found")
+		return nil
+	}
+	return hook
+}
+
+func handleListWebhookDeliveries(repo *repository.WebhookRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		limit, offset, err := parsePagination(r)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		hook := lookupWebhook(w, r, repo)
+		if hook == nil {
+			return
+		}
+
+		deliveries, err := repo.ListDeliveries(r.Context(), hook.ID, limit, offset)
+		if err != nil {
+			writeRepositoryError(w, r, err, "listing webhook deliveries")
+			return
+		}
+		resp := make([]webhookDeliveryResponse, 0, len(deliveries))
+		for _, d := range deliveries {
+			resp = append(resp, newWebhookDeliveryResponse(d))
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"deliveries": resp})
+	}
+}
+
+func handleGetWebhookDelivery(repo *repository.WebhookRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		hook := lookupWebhook(w, r, repo)
+		if hook == nil {
+			return
+		}
+
+		d, err := repo.GetDelivery(r.Context(), hook.ID, chi.URLParam(r, "deliveryID"))
+		if err != nil {
+			writeRepositoryError(w, r, err, "getting webhook delivery")
+			return
+		}
+		if d == nil {
+			writeError(w, http.StatusNotFound, "delivery not found")
+			return
+		}
+		attempts, err := repo.ListAttempts(r.Context(), d.ID)
+		if err != nil {
+			writeRepositoryError(w, r, err, "listing webhook attempts")
+			return
+		}
+
+		resp := newWebhookDeliveryResponse(d)
+		for _, a := range attempts {
+			resp.History = append(resp.History, webhookAttemptResponse{
+				StatusCode: a.StatusCode,
+				Error:      a.Error,
+				DurationMS: a.DurationMS,
+				CreatedAt:  a.CreatedAt,
+			})
+		}
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
+
+func handleRedeliverWebhook(repo *repository.WebhookRepository) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		hook := lookupWebhook(w, r, repo)
+		if hook == nil {
+			return
+		}
+		if !hook.Enabled {
+			writeError(w, http.StatusConflict, "webhook is disabled")
+			return
+		}
+
+		d, err := repo.Redeliver(r.Context(), hook.ID, chi.URLParam(r, "deliveryID"))
+		if err != nil {
+			writeRepositoryError(w, r, err, "redelivering webhook")
+			return
+		}
+		if d == nil {
+			writeError(w, http.StatusNotFound, "delivery not found")
+			return
+		}
+		writeJSON(w, http.StatusAccepted, newWebhookDeliveryResponse(d))
+	}
+}
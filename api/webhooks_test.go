@@ -0,0 +1,84 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testOrgPath = "/v1/organizations/11111111-1111-1111-1111-111111111111"
+
+func TestWebhooksRequireAuth(t *testing.T) {
+	r := testRouter(nil)
+	req := httptest.NewRequest(http.MethodGet, testOrgPath+"/webhooks", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", w.Code)
+	}
+}
+
+func TestWebhooksInvalidOrganizationID(t *testing.T) {
+	r := testRouter(nil)
+	req := httptest.NewRequest(http.MethodGet, "/v1/organizations/not-a-uuid/webhooks", nil)
+	req.Header.Set("Authorization", "Bearer admin")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", w.Code)
+	}
+}
+
+func TestCreateWebhookValidatesURL(t *testing.T) {
+	r := testRouter(nil)
+	for _, body := range []string{
+		`{"url":"ftp://example.com/hook"}`,
+		`{"url":"/relative"}`,
+		`{"url":"http://127.0.0.1:8080/hook"}`,
+		`{"url":"http://10.0.0.5/hook"}`,
+		`{"url":"http://169.254.169.254/latest/meta-data"}`,
+		`{"url":"http://[::1]/hook"}`,
+		`{"url":"http://0.0.0.0/hook"}`,
+		`{"url":"https://example.com","unknown":true}`,
+		``,
+	} {
+		req := httptest.NewRequest(http.MethodPost, testOrgPath+"/webhooks", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer admin")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("body %q: expected 400, got %d", body, w.Code)
+		}
+	}
+}
+
+func TestWebhookInvalidID(t *testing.T) {
+	r := testRouter(nil)
+	req := httptest.NewRequest(http.MethodGet, testOrgPath+"/webhooks/nope/deliveries", nil)
+	req.Header.Set("Authorization", "Bearer admin")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", w.Code)
+	}
+}
+
+func TestParsePagination(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/?limit=10&offset=20", nil)
+	limit, offset, err := parsePagination(req)
+	if err != nil || limit != 10 || offset != 20 {
+		t.Fatalf("parsePagination() = %d, %d, %v", limit, offset, err)
+	}
+
+	for _, q := range []string{"limit=0", "limit=1000", "limit=x", "offset=-1"} {
+		req := httptest.NewRequest(http.MethodGet, "/?"+q, nil)
+		if _, _, err := parsePagination(req); err == nil {
+			t.Errorf("expected error for %q", q)
+		}
+	}
+}
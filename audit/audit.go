@@ -0,0 +1,67 @@
+package audit
+
+import (
+	"context"
+	"log/slog"
+	"reflect"
+)
+
+const (
+	ActorAdmin  = "admin"
+	ActorAPIKey = "api_key"
+	ActorSystem = "system"
+)
+
+type Actor struct {
+	Type string
+	ID   string
+}
+
+func (a Actor) LogValue() slog.Value {
+	if a.ID == "" {
+		return slog.GroupValue(slog.String("type", a.Type))
+	}
+	return slog.GroupValue(slog.String("type", a.Type), slog.String("id", a.ID))
+}
+
+type Metadata struct {
+	Actor     Actor
+	RequestID string
+	SourceIP  string
+}
+
+type metadataKey struct{}
+
+func WithMetadata(ctx context.Context, m Metadata) context.Context {
+	return context.WithValue(ctx, metadataKey{}, m)
+}
+
+// Changes made outside a request are attributed to the system actor.
+func FromContext(ctx context.Context) Metadata {
+	if m, ok := ctx.Value(metadataKey{}).(Metadata); ok {
+		return m
+	}
+	return Metadata{Actor: Actor{Type: ActorSystem}}
+}
+
+type Change struct {
+	Before any `json:"before,omitempty"`
+	After  any `json:"after,omitempty"`
+}
+
+// A nil snapshot stands for a resource that did not exist.
+func Diff(before, after map[string]any) map[string]Change {
+	changes := make(map[string]Change)
+	for k, b := range before {
+		a, ok := after[k]
+		if !ok || !reflect.DeepEqual(a, b) {
+			changes[k] = Change{Before: b, After: a}
+		}
+	}
+	for k, a := range after {
+		if _, ok := before[k]; !ok {
+			changes[k] = Change{After: a}
+		}
+	}
+	return changes
+}
@@ -0,0 +1,42 @@
+package audit
+
+import (
+	"context"
+	"testing"
+)
+
+func TestDiffUpdate(t *testing.T) {
+	changes := Diff(
+		map[string]any{"name": "old", "enabled": true, "event_types": []string{"a"}},
+		map[string]any{"name": "new", "enabled": true, "event_types": []string{"a"}},
+	)
+	if len(changes) != 1 {
+		t.Fatalf("expected 1 change, got %d: %v", len(changes), changes)
+	}
+	if c := changes["name"]; c.Before != "old" || c.After != "new" {
+		t.Fatalf("unexpected name change %+v", c)
+	}
+}
+
+func TestDiffCreateAndDelete(t *testing.T) {
+	created := Diff(nil, map[string]any{"name": "org"})
+	if c := created["name"]; c.Before != nil || c.After != "org" {
+		t.Fatalf("unexpected create change %+v", c)
+	}
+
+	deleted := Diff(map[string]any{"name": "org"}, nil)
+	if c := deleted["name"]; c.Before != "org" || c.After != nil {
+		t.Fatalf("unexpected delete change %+v", c)
+	}
+}
+
+func TestFromContextDefaultsToSystem(t *testing.T) {
+	if m := FromContext(context.Background()); m.Actor.Type != ActorSystem {
+		t.Fatalf("expected system actor, got %+v", m.Actor)
+	}
+
+	ctx := WithMetadata(context.Background(), Metadata{Actor: Actor{Type: ActorAPIKey, ID: "k"}, RequestID: "r"})
+	if m := FromContext(ctx); m.Actor.ID != "k" || m.RequestID != "r" {
+		t.Fatalf("unexpected metadata %+v", m)
+	}
+}
@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/flockiot/flock-api/repository"
+)
+
+var ErrUnauthenticated = errors.New("unauthenticated")
+
+// Admin principals come from the admin token and may access every organization.
+type Principal struct {
+	KeyID          string
+	OrganizationID string
+	Admin          bool
+}
+
+func (p *Principal) CanAccess(organizationID string) bool {
+	return p.Admin || p.OrganizationID == organizationID
+}
+
+type KeyStore interface {
+	GetByToken(ctx context.Context, token string) (*repository.APIKey, error)
+}
+
+type Authenticator struct {
+	adminToken string
+	keys       KeyStore
+}
+
+func NewAuthenticator(adminToken string, keys KeyStore) *Authenticator {
+	return &Authenticator{adminToken: adminToken, keys: keys}
+}
+
+func (a *Authenticator) Authenticate(ctx context.Context, token string) (*Principal, error) {
+	if token == "" {
+		return nil, ErrUnauthenticated
+	}
+	if a.adminToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(a.adminToken)) == 1 {
+		return &Principal{Admin: true}, nil
+	}
+	if a.keys == nil {
+		return nil, ErrUnauthenticated
+	}
+	key, err := a.keys.GetByToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, ErrUnauthenticated
+	}
+	return &Principal{KeyID: key.ID, OrganizationID: key.OrganizationID}, nil
+}
+
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, err := a.Authenticate(r.Context(), TokenFromRequest(r))
+		if err != nil {
+			if !errors.Is(err, ErrUnauthenticated) {
+				slog.ErrorContext(r.Context(), "authentication failed", "error", err)
+				http.Error(w, "internal error", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), p)))
+	})
+}
+
+// TokenFromRequest falls back to the access_token query parameter for browser
+// EventSource and WebSocket clients, which cannot set headers.
+func TokenFromRequest(r *http.Request) string {
+	if h := r.Header.Get("Authorization"); h != "" {
+		scheme, token, ok := strings.Cut(h, " ")
+		if ok && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(token)
+		}
+		return ""
+	}
+	return r.URL.Query().Get("access_token")
+}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+func FromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok
+}
@@ -0,0 +1,104 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/flockiot/flock-api/repository"
+)
+
+type fakeKeys map[string]*repository.APIKey
+
+func (f fakeKeys) GetByToken(_ context.Context, token string) (*repository.APIKey, error) {
+	return f[token], nil
+}
+
+func testAuthenticator() *Authenticator {
+	return NewAuthenticator("admin-secret", fakeKeys{
+		"flk_key": {ID: "key-1", OrganizationID: "org-1"},
+	})
+}
+
+func TestAuthenticateAdmin(t *testing.T) {
+	p, err := testAuthenticator().Authenticate(context.Background(), "admin-secret")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !p.Admin || !p.CanAccess("any-org") {
+		t.Fatal("expected admin principal with access to every organization")
+	}
+}
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	p, err := testAuthenticator().Authenticate(context.Background(), "flk_key")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.Admin {
+		t.Fatal("api key principal should not be admin")
+	}
+	if !p.CanAccess("org-1") || p.CanAccess("org-2") {
+		t.Fatal("api key principal should only access its own organization")
+	}
+}
+
+func TestAuthenticateUnknownToken(t *testing.T) {
+	for _, token := range []string{"", "bogus"} {
+		_, err := testAuthenticator().Authenticate(context.Background(), token)
+		if !errors.Is(err, ErrUnauthenticated) {
+			t.Fatalf("Authenticate(%q) error = %v, want ErrUnauthenticated", token, err)
+		}
+	}
+}
+
+func TestAuthenticateEmptyAdminTokenDisabled(t *testing.T) {
+	a := NewAuthenticator("", nil)
+	if _, err := a.Authenticate(context.Background(), ""); !errors.Is(err, ErrUnauthenticated) {
+		t.Fatalf("expected ErrUnauthenticated, got %v", err)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	handler := testAuthenticator().Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, ok := FromContext(r.Context())
+		if !ok || p.KeyID != "key-1" {
+			t.Errorf("expected principal for key-1 in context, got %+v", p)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without token, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer flk_key")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204 with token, got %d", w.Code)
+	}
+}
+
+func TestTokenFromRequest(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/?access_token=query-token", nil)
+	if got := TokenFromRequest(req); got != "query-token" {
+		t.Fatalf("expected query token, got %q", got)
+	}
+
+	req.Header.Set("Authorization", "bearer header-token")
+	if got := TokenFromRequest(req); got != "header-token" {
+		t.Fatalf("expected header token, got %q", got)
+	}
+
+	req.Header.Set("Authorization", "Basic dXNlcjpwYXNz")
+	if got := TokenFromRequest(req); got != "" {
+		t.Fatalf("expected no token for basic auth, got %q", got)
+	}
+}
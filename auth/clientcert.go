@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"net/http"
+)
+
+// Devices are identified by CommonName or Fingerprint.
+type ClientIdentity struct {
+	Subject      string
+	CommonName   string
+	DNSNames     []string
+	URIs         []string
+	SerialNumber string
+	Fingerprint  string
+}
+
+// Certificates that were presented but not verified are ignored.
+func ClientIdentityFromTLS(state *tls.ConnectionState) (*ClientIdentity, bool) {
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return nil, false
+	}
+	cert := state.VerifiedChains[0][0]
+	sum := sha256.Sum256(cert.Raw)
+	id := &ClientIdentity{
+		Subject:      cert.Subject.String(),
+		CommonName:   cert.Subject.CommonName,
+		DNSNames:     cert.DNSNames,
+		SerialNumber: cert.SerialNumber.Text(16),
+		Fingerprint:  hex.EncodeToString(sum[:]),
+	}
+	for _, u := range cert.URIs {
+		id.URIs = append(id.URIs, u.String())
+	}
+	return id, true
+}
+
+type clientIdentityKey struct{}
+
+func WithClientIdentity(ctx context.Context, id *ClientIdentity) context.Context {
+	return context.WithValue(ctx, clientIdentityKey{}, id)
+}
+
+func ClientIdentityFromContext(ctx context.Context) (*ClientIdentity, bool) {
+	id, ok := ctx.Value(clientIdentityKey{}).(*ClientIdentity)
+	return id, ok
+}
+
+// ClientCertificate does not reject requests without a certificate; the
+// listener's client auth mode decides that.
+func ClientCertificate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if id, ok := ClientIdentityFromTLS(r.TLS); ok {
+			r = r.WithContext(WithClientIdentity(r.Context(), id))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,153 @@
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/flockiot/flock-api/config"
+)
+
+// checkInterval keeps a busy listener from statting the files every handshake.
+const checkInterval = 10 * time.Second
+
+type Files struct {
+	certFile   string
+	keyFile    string
+	caFile     string
+	minVersion uint16
+	clientAuth tls.ClientAuthType
+
+	mu        sync.Mutex
+	config    *tls.Config
+	modTimes  []time.Time
+	lastCheck time.Time
+}
+
+// New returns nil when TLS is not configured.
+func New(cfg config.ServerConfig) (*Files, error) {
+	if cfg.TLSCert == "" {
+		return nil, nil
+	}
+	f := &Files{
+		certFile:   cfg.TLSCert,
+		keyFile:    cfg.TLSKey,
+		caFile:     cfg.TLSClientCA,
+		minVersion: tls.VersionTLS12,
+		clientAuth: tls.NoClientCert,
+	}
+	switch cfg.TLSMinVersion {
+	case "1.2":
+	case "1.3":
+		f.minVersion = tls.VersionTLS13
+	default:
+		return nil, fmt.Errorf("unknown TLS version %q (valid: 1.2, 1.3)", cfg.TLSMinVersion)
+	}
+	if f.caFile != "" {
+		switch cfg.TLSClientAuth {
+		case "required":
+			f.clientAuth = tls.RequireAndVerifyClientCert
+		case "optional", "":
+			f.clientAuth = tls.VerifyClientCertIfGiven
+		default:
+			return nil, fmt.Errorf("unknown client auth mode %q (valid: required, optional)", cfg.TLSClientAuth)
+		}
+	}
+
+	modTimes, err := f.stat()
+	if err != nil {
+		return nil, err
+	}
+	if f.config, err = f.load(); err != nil {
+		return nil, err
+	}
+	f.modTimes = modTimes
+	f.lastCheck = time.Now()
+	return f, nil
+}
+
+func (f *Files) TLSConfig(nextProtos ...string) *tls.Config {
+	return &tls.Config{
+		MinVersion: f.minVersion,
+		NextProtos: nextProtos,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			c := f.current().Clone()
+			c.NextProtos = nextProtos
+			return c, nil
+		},
+	}
+}
+
+// A failed reload, e.g. mid-rotation with a new certificate but the old key,
+// keeps the previous configuration and is retried later.
+func (f *Files) current() *tls.Config {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if time.Since(f.lastCheck) < checkInterval {
+		return f.config
+	}
+	f.lastCheck = time.Now()
+
+	modTimes, err := f.stat()
+	if err != nil {
+		slog.Error("checking TLS files failed", "error", err)
+		return f.config
+	}
+	if slices.EqualFunc(modTimes, f.modTimes, time.Time.Equal) {
+		return f.config
+	}
+	c, err := f.load()
+	if err != nil {
+		slog.Error("reloading TLS files failed, keeping the previous certificate", "error", err)
+		return f.config
+	}
+	f.config = c
+	f.modTimes = modTimes
+	slog.Info("TLS certificate reloaded", "cert", f.certFile)
+	return c
+}
+
+func (f *Files) stat() ([]time.Time, error) {
+	var times []time.Time
+	for _, name := range []string{f.certFile, f.keyFile, f.caFile} {
+		if name == "" {
+			continue
+		}
+		info, err := os.Stat(name)
+		if err != nil {
+			return nil, fmt.Errorf("reading TLS file: %w", err)
+		}
+		times = append(times, info.ModTime())
+	}
+	return times, nil
+}
+
+func (f *Files) load() (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(f.certFile, f.keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("loading TLS certificate: %w", err)
+	}
+	c := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   f.minVersion,
+		ClientAuth:   f.clientAuth,
+	}
+	if f.caFile != "" {
+		pem, err := os.ReadFile(f.caFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading client CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New("client CA file contains no certificates")
+		}
+		c.ClientCAs = pool
+	}
+	return c, nil
+}
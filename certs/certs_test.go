@@ -0,0 +1,203 @@
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/flockiot/flock-api/auth"
+	"github.com/flockiot/flock-api/config"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	tls  tls.Certificate
+}
+
+func issue(t *testing.T, cn string, parent *testCert) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	signer, signerKey := tmpl, key
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCert{cert: cert, key: key, tls: tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}}
+}
+
+func (c *testCert) write(t *testing.T, dir, name string) (certFile, keyFile string) {
+	t.Helper()
+	keyDER, err := x509.MarshalECPrivateKey(c.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile = filepath.Join(dir, name+".crt")
+	keyFile = filepath.Join(dir, name+".key")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	if err := os.WriteFile(certFile, certPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, keyPEM, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func serve(t *testing.T, files *Files, h http.Handler) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewUnstartedServer(h)
+	srv.TLS = files.TLSConfig("http/1.1")
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func client(ca *testCert, cert *testCert) *http.Client {
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	cfg := &tls.Config{RootCAs: roots}
+	if cert != nil {
+		cfg.Certificates = []tls.Certificate{cert.tls}
+	}
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: cfg}}
+}
+
+func TestNewWithoutTLS(t *testing.T) {
+	files, err := New(config.ServerConfig{})
+	if files != nil || err != nil {
+		t.Fatalf("New() = %v, %v; want nil, nil", files, err)
+	}
+}
+
+func TestMutualTLSExposesClientIdentity(t *testing.T) {
+	dir := t.TempDir()
+	ca := issue(t, "test-ca", nil)
+	certFile, keyFile := issue(t, "server", ca).write(t, dir, "server")
+	caFile, _ := ca.write(t, dir, "ca")
+
+	files, err := New(config.ServerConfig{
+		TLSCert: certFile, TLSKey: keyFile, TLSMinVersion: "1.2",
+		TLSClientCA: caFile, TLSClientAuth: "required",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got *auth.ClientIdentity
+	srv := serve(t, files, auth.ClientCertificate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = auth.ClientIdentityFromContext(r.Context())
+	})))
+
+	device := issue(t, "device-42", ca)
+	resp, err := client(ca, device).Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got == nil || got.CommonName != "device-42" || got.Fingerprint == "" {
+		t.Fatalf("identity = %+v, want device-42", got)
+	}
+
+	if _, err := client(ca, nil).Get(srv.URL); err == nil {
+		t.Fatal("a client without a certificate should be rejected")
+	}
+	if _, err := client(ca, issue(t, "stranger", issue(t, "other-ca", nil))).Get(srv.URL); err == nil {
+		t.Fatal("a certificate from another CA should be rejected")
+	}
+}
+
+func TestOptionalClientAuthAdmitsClientsWithoutCertificate(t *testing.T) {
+	dir := t.TempDir()
+	ca := issue(t, "test-ca", nil)
+	certFile, keyFile := issue(t, "server", ca).write(t, dir, "server")
+	caFile, _ := ca.write(t, dir, "ca")
+
+	files, err := New(config.ServerConfig{TLSCert: certFile, TLSKey: keyFile, TLSMinVersion: "1.2", TLSClientCA: caFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := serve(t, files, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {}))
+
+	resp, err := client(ca, nil).Get(srv.URL)
+	if err != nil {
+		t.Fatalf("a health probe without a certificate should be served: %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestReloadsRotatedCertificate(t *testing.T) {
+	dir := t.TempDir()
+	ca := issue(t, "test-ca", nil)
+	certFile, keyFile := issue(t, "first", ca).write(t, dir, "server")
+
+	files, err := New(config.ServerConfig{TLSCert: certFile, TLSKey: keyFile, TLSMinVersion: "1.3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := serve(t, files, http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	served := func() string {
+		t.Helper()
+		resp, err := client(ca, nil).Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.TLS.Version != tls.VersionTLS13 {
+			t.Errorf("negotiated version %x, want TLS 1.3", resp.TLS.Version)
+		}
+		return resp.TLS.PeerCertificates[0].Subject.CommonName
+	}
+	if cn := served(); cn != "first" {
+		t.Fatalf("served %q, want first", cn)
+	}
+
+	issue(t, "second", ca).write(t, dir, "server")
+	later := time.Now().Add(time.Minute)
+	for _, name := range []string{certFile, keyFile} {
+		if err := os.Chtimes(name, later, later); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files.mu.Lock()
+	files.lastCheck = time.Time{}
+	files.mu.Unlock()
+
+	if cn := served(); cn != "second" {
+		t.Fatalf("served %q after rotation, want second", cn)
+	}
+}
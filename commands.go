@@ -0,0 +1,303 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/flockiot/flock-api/config"
+	"github.com/flockiot/flock-api/database"
+)
+
+const configFlagUsage = "YAML or TOML config file; environment variables and flags override it"
+
+func runConfigCommand(args []string) int {
+	if len(args) == 0 || args[0] != "print" {
+		fmt.Fprintln(os.Stderr, "usage: flock-api config print [-config file] [-<setting>=value ...]")
+		return 2
+	}
+
+	fs := flag.NewFlagSet("config print", flag.ContinueOnError)
+	configFile := fs.String("config", os.Getenv("FLOCK_CONFIG"), configFlagUsage)
+	overrides := config.BindFlags(fs)
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	cfg, sources, err := config.LoadWithOptions(config.Options{File: *configFile, Flags: overrides})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error loading config: %v\n", err)
+		return 1
+	}
+	if err := config.Print(os.Stdout, cfg, sources); err != nil {
+		fmt.Fprintf(os.Stderr, "error printing config: %v\n", err)
+		return 1
+	}
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+const migrateUsage = `usage: flock-api migrate <command> [arg] [-config file] [-yes] [-<setting>=value ...]
+
+commands:
+  status          show the schema version and whether it is dirty
+  up              apply every pending migration
+  goto VERSION    migrate up or down to VERSION
+  down N          roll back the last N migrations
+  force VERSION   mark VERSION as applied and clean after a failed migration
+  lint            check the migration files for unsafe patterns
+  drift           compare the database schema with what the migrations produce`
+
+type migrateArgs struct {
+	command    string
+	arg        uint
+	configFile string
+	yes        bool
+	overrides  map[string]string
+}
+
+// Flags may come before or after the command's argument.
+func parseMigrateArgs(args []string) (*migrateArgs, error) {
+	if len(args) == 0 {
+		return nil, errors.New("no command given")
+	}
+	a := &migrateArgs{command: args[0]}
+
+	fs := flag.NewFlagSet("migrate "+a.command, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.StringVar(&a.configFile, "config", os.Getenv("FLOCK_CONFIG"), configFlagUsage)
+	fs.BoolVar(&a.yes, "yes", false, "do not ask for confirmation")
+	a.overrides = config.BindFlags(fs)
+	var positional []string
+	for rest := args[1:]; ; {
+		if err := fs.Parse(rest); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		positional = append(positional, fs.Arg(0))
+		rest = fs.Args()[1:]
+	}
+
+	switch a.command {
+	case "status", "up", "lint", "drift":
+		if len(positional) != 0 {
+			return nil, fmt.Errorf("%s takes no argument", a.command)
+		}
+	case "goto", "down", "force":
+		if len(positional) != 1 {
+			return nil, fmt.Errorf("%s takes one argument", a.command)
+		}
+		n, err := strconv.ParseUint(positional[0], 10, 0)
+		if err != nil || (a.command == "down" && n == 0) {
+			return nil, fmt.Errorf("invalid argument %q for %s", positional[0], a.command)
+		}
+		a.arg = uint(n)
+	default:
+		return nil, fmt.Errorf("unknown command %q", a.command)
+	}
+	return a, nil
+}
+
+func runMigrateCommand(args []string) int {
+	a, err := parseMigrateArgs(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n%s\n", err, migrateUsage)
+		return 2
+	}
+	command, arg, yes := a.command, a.arg, a.yes
+
+	if command == "lint" {
+		return runMigrationLint()
+	}
+
+	available, err := database.Migrations()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	if (command == "goto" || command == "force") && arg != 0 && migrationIndex(available, arg) < 0 {
+		fmt.Fprintf(os.Stderr, "error: no migration with version %d\n", arg)
+		return 1
+	}
+
+	cfg, _, err := config.LoadWithOptions(config.Options{File: a.configFile, Flags: a.overrides})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error loading config: %v\n", err)
+		return 1
+	}
+	if command == "drift" {
+		return runMigrationDrift(cfg.Postgres.MigrationURL())
+	}
+	m, err := database.NewMigrator(cfg.Postgres.MigrationURL())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	defer m.Close()
+
+	current, dirty, err := m.Version()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+
+	switch command {
+	case "status":
+		printMigrationStatus(available, current, dirty)
+		return 0
+	case "up":
+		err = m.Up()
+	case "goto":
+		if arg < current && !confirm(fmt.Sprintf("Roll back %s?", describeMigrations(available, arg, current)), yes) {
+			return 1
+		}
+		err = m.Goto(arg)
+	case "down":
+		var target uint
+		if target, err = downTarget(available, current, arg); err != nil {
+			break
+		}
+		if !confirm(fmt.Sprintf("Roll back %s?", describeMigrations(available, target, current)), yes) {
+			return 1
+		}
+		err = m.Down(int(arg))
+	case "force":
+		state := "clean"
+		if dirty {
+			state = "dirty"
+		}
+		prompt := fmt.Sprintf("The schema is at version %d (%s). Record version %d as applied and clean without running any migration?", current, state, arg)
+		if !confirm(prompt, yes) {
+			return 1
+		}
+		err = m.Force(arg)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+
+	current, dirty, err = m.Version()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	printMigrationStatus(available, current, dirty)
+	return 0
+}
+
+func runMigrationLint() int {
+	issues, err := database.Lint()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	for _, issue := range issues {
+		fmt.Println(issue)
+	}
+	if len(issues) > 0 {
+		return 1
+	}
+	fmt.Println("migrations OK")
+	return 0
+}
+
+func runMigrationDrift(dsn string) int {
+	diffs, err := database.Drift(context.Background(), dsn)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	for _, d := range diffs {
+		fmt.Println(d)
+	}
+	if len(diffs) > 0 {
+		return 1
+	}
+	fmt.Println("schema matches the migrations")
+	return 0
+}
+
+func migrationIndex(list []database.Migration, version uint) int {
+	return slices.IndexFunc(list, func(m database.Migration) bool { return m.Version == version })
+}
+
+func printMigrationStatus(available []database.Migration, current uint, dirty bool) {
+	name := "none"
+	if i := migrationIndex(available, current); i >= 0 {
+		name = available[i].Name
+	}
+	fmt.Printf("version: %d (%s)\n", current, name)
+	fmt.Printf("dirty:   %t\n", dirty)
+	pending := 0
+	for _, m := range available {
+		if m.Version > current {
+			pending++
+		}
+	}
+	if len(available) > 0 {
+		latest := available[len(available)-1]
+		fmt.Printf("latest:  %d (%s), %d pending\n", latest.Version, latest.Name, pending)
+	}
+	if dirty {
+		fmt.Printf("a migration to version %d failed; repair the schema, then run \"flock-api migrate force VERSION\"\n", current)
+	}
+}
+
+func downTarget(available []database.Migration, current, n uint) (uint, error) {
+	if current == 0 {
+		return 0, errors.New("no migrations are applied")
+	}
+	i := migrationIndex(available, current)
+	if i < 0 {
+		return 0, fmt.Errorf("the schema is at version %d, which this binary does not know; use a newer binary or \"migrate goto\"", current)
+	}
+	if i < int(n) {
+		return 0, nil
+	}
+	return available[i-int(n)].Version, nil
+}
+
+// describeMigrations lists versions in (from, to].
+func describeMigrations(available []database.Migration, from, to uint) string {
+	var names []string
+	for i := len(available) - 1; i >= 0; i-- {
+		if m := available[i]; m.Version > from && m.Version <= to {
+			names = append(names, fmt.Sprintf("%d_%s", m.Version, m.Name))
+		}
+	}
+	if len(names) == 0 {
+		return "nothing"
+	}
+	return strings.Join(names, ", ")
+}
+
+// confirm refuses when stdin is not a terminal.
+func confirm(prompt string, yes bool) bool {
+	if yes {
+		return true
+	}
+	if info, err := os.Stdin.Stat(); err != nil || info.Mode()&os.ModeCharDevice == 0 {
+		fmt.Fprintf(os.Stderr, "%s\nrefusing without a terminal to confirm; pass -yes to proceed\n", prompt)
+		return false
+	}
+	fmt.Fprintf(os.Stderr, "%s Type \"yes\" to continue: ", prompt)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	if strings.TrimSpace(answer) != "yes" {
+		fmt.Fprintln(os.Stderr, "aborted")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/flockiot/flock-api/database"
+)
+
+func TestParseMigrateArgs(t *testing.T) {
+	cases := []struct {
+		args    []string
+		command string
+		arg     uint
+		yes     bool
+	}{
+		{args: []string{"status"}, command: "status"},
+		{args: []string{"down", "2", "-yes"}, command: "down", arg: 2, yes: true},
+		{args: []string{"goto", "-yes", "3"}, command: "goto", arg: 3, yes: true},
+		{args: []string{"force", "-postgres.dsn=postgres://db/flock", "0"}, command: "force"},
+	}
+	for _, tc := range cases {
+		a, err := parseMigrateArgs(tc.args)
+		if err != nil {
+			t.Errorf("parseMigrateArgs(%q) error: %v", tc.args, err)
+			continue
+		}
+		if a.command != tc.command || a.arg != tc.arg || a.yes != tc.yes {
+			t.Errorf("parseMigrateArgs(%q) = %+v, want command %s, arg %d, yes %t", tc.args, a, tc.command, tc.arg, tc.yes)
+		}
+	}
+	if a, _ := parseMigrateArgs([]string{"force", "-postgres.dsn=postgres://db/flock", "0"}); a.overrides["postgres.dsn"] != "postgres://db/flock" {
+		t.Errorf("overrides = %v, want postgres.dsn", a.overrides)
+	}
+
+	for _, args := range [][]string{
+		nil,
+		{"sideways"},
+		{"up", "3"},
+		{"goto"},
+		{"goto", "1", "2"},
+		{"goto", "-1"},
+		{"down", "0"},
+		{"down", "two"},
+		{"status", "-unknown"},
+	} {
+		if _, err := parseMigrateArgs(args); err == nil {
+			t.Errorf("parseMigrateArgs(%q) succeeded, want an error", args)
+		}
+	}
+}
+
+var testMigrations = []database.Migration{{Version: 1, Name: "init"}, {Version: 2, Name: "webhooks"}, {Version: 5, Name: "events"}}
+
+func TestDescribeMigrations(t *testing.T) {
+	cases := []struct {
+		from, to uint
+		want     string
+	}{
+		{2, 5, "5_events"},
+		{0, 5, "5_events, 2_webhooks, 1_init"},
+		{1, 2, "2_webhooks"},
+		{5, 5, "nothing"},
+	}
+	for _, tc := range cases {
+		if got := describeMigrations(testMigrations, tc.from, tc.to); got != tc.want {
+			t.Errorf("describeMigrations(%d, %d) = %q, want %q", tc.from, tc.to, got, tc.want)
+		}
+	}
+}
+
+func TestDownTarget(t *testing.T) {
+	cases := []struct {
+		current, n, want uint
+	}{
+		{5, 1, 2},
+		{5, 2, 1},
+		{5, 3, 0},
+		{5, 10, 0},
+	}
+	for _, tc := range cases {
+		if got, err := downTarget(testMigrations, tc.current, tc.n); err != nil || got != tc.want {
+			t.Errorf("downTarget(%d, %d) = %d, %v; want %d", tc.current, tc.n, got, err, tc.want)
+		}
+	}
+	for _, current := range []uint{0, 3, 7} {
+		if _, err := downTarget(testMigrations, current, 1); err == nil {
+			t.Errorf("downTarget(%d, 1) succeeded, want an error", current)
+		}
+	}
+}
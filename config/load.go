@@ -0,0 +1,305 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/caarlos0/env/v11"
+	"gopkg.in/yaml.v3"
+)
+
+const envPrefix = "FLOCK_"
+
+// Later layers win: defaults, File, the environment, then Flags.
+type Options struct {
+	// File is YAML or TOML; FLOCK_RATE_LIMIT_KEY_BY is key_by under rate_limit.
+	File string
+	// Environ defaults to os.Environ().
+	Environ []string
+	Flags   map[string]string
+}
+
+type Setting struct {
+	Name       string // dotted name used in files and flags, e.g. "postgres.dsn"
+	Env        string // environment variable, e.g. "FLOCK_POSTGRES_DSN"
+	Secret     bool
+	Reloadable bool
+
+	index []int
+}
+
+// Sources maps a setting name to "default", "file <path>", "env <VAR>" or
+// "flag -<name>".
+type Sources map[string]string
+
+func Settings() []Setting {
+	var out []Setting
+	walk(reflect.TypeOf(Config{}), nil, "", envPrefix, &out)
+	return out
+}
+
+func walk(t reflect.Type, index []int, name, envName string, out *[]Setting) {
+	for i := range t.NumField() {
+		f := t.Field(i)
+		idx := append(slices.Clone(index), i)
+		if prefix, ok := f.Tag.Lookup("envPrefix"); ok {
+			section := strings.ToLower(strings.TrimSuffix(prefix, "_"))
+			walk(f.Type, idx, joinName(name, section), envName+prefix, out)
+			continue
+		}
+		key, _, _ := strings.Cut(f.Tag.Get("env"), ",")
+		if key == "" {
+			continue
+		}
+		*out = append(*out, Setting{
+			Name:       joinName(name, strings.ToLower(key)),
+			Env:        envName + key,
+			Secret:     f.Tag.Get("secret") == "true",
+			Reloadable: f.Tag.Get("reload") == "true",
+			index:      idx,
+		})
+	}
+}
+
+func joinName(parent, child string) string {
+	if parent == "" {
+		return child
+	}
+	return parent + "." + child
+}
+
+func Load() (*Config, error) {
+	cfg, _, err := LoadWithOptions(Options{})
+	return cfg, err
+}
+
+func LoadWithOptions(opts Options) (*Config, Sources, error) {
+	settings := Settings()
+	vars := make(map[string]string)
+	sources := make(Sources, len(settings))
+	for _, s := range settings {
+		sources[s.Name] = "default"
+	}
+
+	if opts.File != "" {
+		values, err := readFile(opts.File, settings)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, s := range settings {
+			if v, ok := values[s.Name]; ok {
+				vars[s.Env] = v
+				sources[s.Name] = "file " + opts.File
+			}
+		}
+	}
+
+	environ := opts.Environ
+	if environ == nil {
+		environ = os.Environ()
+	}
+	envVars := make(map[string]string, len(environ))
+	for _, kv := range environ {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			envVars[k] = v
+		}
+	}
+	for _, s := range settings {
+		v, set := envVars[s.Env]
+		path, fromFile := envVars[s.Env+"_FILE"]
+		switch {
+		case set && fromFile:
+			return nil, nil, fmt.Errorf("both %s and %s_FILE are set", s.Env, s.Env)
+		case fromFile:
+			b, err := os.ReadFile(path)
+			if err != nil {
+				return nil, nil, fmt.Errorf("reading %s_FILE: %w", s.Env, err)
+			}
+			vars[s.Env] = strings.TrimRight(string(b), "\r\n")
+			sources[s.Name] = "env " + s.Env + "_FILE"
+		case set:
+			vars[s.Env] = v
+			sources[s.Name] = "env " + s.Env
+		}
+	}
+
+	for name, v := range opts.Flags {
+		s, ok := settingByName(settings, name)
+		if !ok {
+			return nil, nil, fmt.Errorf("unknown setting %q", name)
+		}
+		vars[s.Env] = v
+		sources[s.Name] = "flag -" + s.Name
+	}
+
+	cfg, err := env.ParseAsWithOptions[Config](env.Options{
+		Prefix:      envPrefix,
+		Environment: vars,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return &cfg, sources, nil
+}
+
+func settingByName(settings []Setting, name string) (Setting, bool) {
+	for _, s := range settings {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return Setting{}, false
+}
+
+// Unknown keys are rejected so typos do not go unnoticed.
+func readFile(path string, settings []Setting) (map[string]string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config file: %w", err)
+	}
+	var doc map[string]any
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(b, &doc)
+	case ".toml":
+		err = toml.Unmarshal(b, &doc)
+	default:
+		return nil, fmt.Errorf("config file %s: unsupported format %q (use .yaml, .yml or .toml)", path, ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+
+	known := make(map[string]bool, len(settings))
+	for _, s := range settings {
+		known[s.Name] = true
+	}
+	values := make(map[string]string)
+	for section, v := range doc {
+		if v == nil {
+			continue
+		}
+		keys, ok := v.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("config file %s: %s must be a section", path, section)
+		}
+		for key, v := range keys {
+			name := section + "." + key
+			if !known[name] {
+				return nil, fmt.Errorf("config file %s: unknown setting %q", path, name)
+			}
+			values[name] = envString(v)
+		}
+	}
+	return values, nil
+}
+
+func envString(v any) string {
+	switch v := v.(type) {
+	case []any:
+		parts := make([]string, len(v))
+		for i, item := range v {
+			parts[i] = envString(item)
+		}
+		return strings.Join(parts, ",")
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		parts := make([]string, len(keys))
+		for i, k := range keys {
+			parts[i] = k + "=" + envString(v[k])
+		}
+		return strings.Join(parts, ",")
+	case time.Time:
+		return v.Format(time.RFC3339)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// BindFlags returns the map that fs.Parse fills with the flags actually given.
+func BindFlags(fs *flag.FlagSet) map[string]string {
+	values := make(map[string]string)
+	for _, s := range Settings() {
+		fs.Func(s.Name, "overrides "+s.Env, func(v string) error {
+			values[s.Name] = v
+			return nil
+		})
+	}
+	return values
+}
+
+func (s Setting) Value(cfg *Config) string {
+	v := reflect.ValueOf(cfg).Elem().FieldByIndex(s.index)
+	if s.Secret && v.Kind() == reflect.Slice {
+		parts := make([]string, v.Len())
+		for i := range parts {
+			parts[i] = mask(fmt.Sprint(v.Index(i).Interface()))
+		}
+		return strings.Join(parts, ",")
+	}
+	text := formatValue(v)
+	if s.Secret {
+		return mask(text)
+	}
+	return text
+}
+
+func formatValue(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Slice:
+		parts := make([]string, v.Len())
+		for i := range parts {
+			parts[i] = fmt.Sprint(v.Index(i).Interface())
+		}
+		return strings.Join(parts, ",")
+	case reflect.Map:
+		parts := make([]string, 0, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			parts = append(parts, fmt.Sprintf("%v=%v", iter.Key().Interface(), iter.Value().Interface()))
+		}
+		sort.Strings(parts)
+		return strings.Join(parts, ",")
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
+
+// For URLs only the password is hidden; the rest helps debug connections.
+func mask(s string) string {
+	if s == "" {
+		return ""
+	}
+	if u, err := url.Parse(s); err == nil && u.Scheme != "" && u.User != nil {
+		if _, ok := u.User.Password(); !ok {
+			return s
+		}
+		u.User = url.UserPassword(u.User.Username(), "xxxxx")
+		return u.String()
+	}
+	return "********"
+}
+
+func Print(w io.Writer, cfg *Config, sources Sources) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SETTING\tVALUE\tSOURCE")
+	for _, s := range Settings() {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", s.Name, s.Value(cfg), sources[s.Name])
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,152 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadLayers(t *testing.T) {
+	file := writeFile(t, "flock.yaml", `
+server:
+  host: file-host
+  port: 7000
+  grpc_port: 7001
+events:
+  allowed_origins: [https://a.example, https://b.example]
+rate_limit:
+  tiers:
+    default: 10/s
+    gold: 100/s:50
+`)
+	cfg, sources, err := LoadWithOptions(Options{
+		File:    file,
+		Environ: []string{"FLOCK_SERVER_PORT=7100", "FLOCK_SERVER_GRPC_PORT=7101"},
+		Flags:   map[string]string{"server.grpc_port": "7201"},
+	})
+	if err != nil {
+		t.Fatalf("LoadWithOptions() error: %v", err)
+	}
+
+	if cfg.Server.Host != "file-host" || sources["server.host"] != "file "+file {
+		t.Errorf("server.host = %q from %q, want file value", cfg.Server.Host, sources["server.host"])
+	}
+	if cfg.Server.Port != 7100 || sources["server.port"] != "env FLOCK_SERVER_PORT" {
+		t.Errorf("server.port = %d from %q, want env override", cfg.Server.Port, sources["server.port"])
+	}
+	if cfg.Server.GRPCPort != 7201 || sources["server.grpc_port"] != "flag -server.grpc_port" {
+		t.Errorf("server.grpc_port = %d from %q, want flag override", cfg.Server.GRPCPort, sources["server.grpc_port"])
+	}
+	if cfg.Server.AdminPort != 9091 || sources["server.admin_port"] != "default" {
+		t.Errorf("server.admin_port = %d from %q, want default", cfg.Server.AdminPort, sources["server.admin_port"])
+	}
+	if len(cfg.Events.AllowedOrigins) != 2 || cfg.Events.AllowedOrigins[1] != "https://b.example" {
+		t.Errorf("events.allowed_origins = %v, want list from file", cfg.Events.AllowedOrigins)
+	}
+	if cfg.RateLimit.Tiers["gold"] != "100/s:50" {
+		t.Errorf("rate_limit.tiers = %v, want map from file", cfg.RateLimit.Tiers)
+	}
+}
+
+func TestLoadTOML(t *testing.T) {
+	file := writeFile(t, "flock.toml", `
+[webhooks]
+timeout = "3s"
+concurrency = 2
+`)
+	cfg, _, err := LoadWithOptions(Options{File: file, Environ: []string{}})
+	if err != nil {
+		t.Fatalf("LoadWithOptions() error: %v", err)
+	}
+	if cfg.Webhooks.Timeout != 3*time.Second || cfg.Webhooks.Concurrency != 2 {
+		t.Errorf("Webhooks = %+v, want values from file", cfg.Webhooks)
+	}
+}
+
+func TestLoadRejectsUnknownFileKeys(t *testing.T) {
+	file := writeFile(t, "flock.yaml", "server:\n  prot: 1\n")
+	if _, _, err := LoadWithOptions(Options{File: file, Environ: []string{}}); err == nil || !strings.Contains(err.Error(), "server.prot") {
+		t.Fatalf("expected unknown setting error, got %v", err)
+	}
+}
+
+func TestLoadEnvFile(t *testing.T) {
+	secret := writeFile(t, "dsn", "postgres://app:hunter2@db:5432/flock\n")
+	cfg, sources, err := LoadWithOptions(Options{Environ: []string{"FLOCK_POSTGRES_DSN_FILE=" + secret}})
+	if err != nil {
+		t.Fatalf("LoadWithOptions() error: %v", err)
+	}
+	if cfg.Postgres.DSN != "postgres://app:hunter2@db:5432/flock" {
+		t.Errorf("Postgres.DSN = %q, want file contents without newline", cfg.Postgres.DSN)
+	}
+	if sources["postgres.dsn"] != "env FLOCK_POSTGRES_DSN_FILE" {
+		t.Errorf("source = %q, want env FLOCK_POSTGRES_DSN_FILE", sources["postgres.dsn"])
+	}
+
+	_, _, err = LoadWithOptions(Options{Environ: []string{"FLOCK_POSTGRES_DSN_FILE=" + secret, "FLOCK_POSTGRES_DSN=x"}})
+	if err == nil {
+		t.Fatal("expected error when both a variable and its _FILE are set")
+	}
+}
+
+func TestLoadRejectsUnknownFlags(t *testing.T) {
+	if _, _, err := LoadWithOptions(Options{Environ: []string{}, Flags: map[string]string{"server.nope": "1"}}); err == nil {
+		t.Fatal("expected error for unknown setting")
+	}
+}
+
+func TestSettingValueMasksSecrets(t *testing.T) {
+	cfg := &Config{
+		Postgres: PostgresConfig{
+			DSN:         "postgres://app:hunter2@db:5432/flock",
+			ReplicaDSNs: []string{"postgres://app:hunter2@r1/flock", "postgres://app:hunter2@r2/flock"},
+		},
+		Auth: AuthConfig{AdminToken: "hunter2"},
+	}
+	for _, s := range Settings() {
+		switch s.Name {
+		case "postgres.dsn":
+			if got := s.Value(cfg); got != "postgres://app:xxxxx@db:5432/flock" {
+				t.Errorf("postgres.dsn = %q, want password masked", got)
+			}
+		case "postgres.replica_dsns":
+			if got := s.Value(cfg); got != "postgres://app:xxxxx@r1/flock,postgres://app:xxxxx@r2/flock" {
+				t.Errorf("postgres.replica_dsns = %q, want each password masked", got)
+			}
+		case "auth.admin_token":
+			if got := s.Value(cfg); got != "********" {
+				t.Errorf("auth.admin_token = %q, want masked", got)
+			}
+		}
+	}
+}
+
+func TestPrint(t *testing.T) {
+	cfg, sources, err := LoadWithOptions(Options{Environ: []string{"FLOCK_AUTH_ADMIN_TOKEN=hunter2"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := Print(&buf, cfg, sources); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if strings.Contains(out, "hunter2") {
+		t.Fatalf("secret leaked into output:\n%s", out)
+	}
+	if !strings.Contains(out, "env FLOCK_AUTH_ADMIN_TOKEN") {
+		t.Fatalf("expected source in output:\n%s", out)
+	}
+}
@@ -0,0 +1,146 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Applied is false for settings that need a restart.
+type Change struct {
+	Setting string `json:"setting"`
+	Old     string `json:"old"`
+	New     string `json:"new"`
+	Applied bool   `json:"applied"`
+}
+
+// Flags and the process environment cannot change, so a reload picks up
+// edits to the config file and to files named by _FILE variables.
+type Reloader struct {
+	opts Options
+
+	mu  sync.Mutex
+	cfg *Config
+}
+
+func NewReloader(cfg *Config, opts Options) *Reloader {
+	return &Reloader{opts: opts, cfg: cfg}
+}
+
+func (r *Reloader) Current() *Config {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.cfg
+}
+
+// Reload reports changes to settings that need a restart but does not apply
+// them. On error nothing changes.
+func (r *Reloader) Reload() ([]Change, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	loaded, _, err := LoadWithOptions(r.opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := loaded.Validate(); err != nil {
+		return nil, err
+	}
+
+	next := *r.cfg
+	var (
+		changes []Change
+		names   []string
+		applied []string
+		pending []string
+	)
+	for _, s := range Settings() {
+		oldValue := reflect.ValueOf(r.cfg).Elem().FieldByIndex(s.index)
+		newValue := reflect.ValueOf(loaded).Elem().FieldByIndex(s.index)
+		if reflect.DeepEqual(oldValue.Interface(), newValue.Interface()) {
+			continue
+		}
+		c := Change{Setting: s.Name, Old: s.Value(r.cfg), New: s.Value(loaded), Applied: s.Reloadable}
+		changes = append(changes, c)
+		desc := fmt.Sprintf("%s: %q -> %q", c.Setting, c.Old, c.New)
+		if !s.Reloadable {
+			pending = append(pending, desc)
+			continue
+		}
+		reflect.ValueOf(&next).Elem().FieldByIndex(s.index).Set(newValue)
+		names = append(names, c.Setting)
+		applied = append(applied, desc)
+	}
+	if len(pending) > 0 {
+		slog.Warn("configuration changes need a restart to take effect", "changes", pending)
+	}
+	if len(names) == 0 {
+		slog.Info("configuration reloaded, nothing to apply")
+		return changes, nil
+	}
+
+	r.cfg = &next
+	slog.Info("configuration reloaded", "changes", applied)
+	publish(&next, names)
+	return changes, nil
+}
+
+type subscription struct {
+	ctx    context.Context
+	prefix string
+	fn     func(*Config)
+}
+
+var subscriptions struct {
+	sync.Mutex
+	subs   map[*subscription]struct{}
+	latest *Config
+}
+
+// If a reload has already happened, Subscribe calls fn right away so a
+// subsystem built from the startup configuration catches up.
+func Subscribe(ctx context.Context, prefix string, fn func(*Config)) {
+	sub := &subscription{ctx: ctx, prefix: prefix, fn: fn}
+
+	subscriptions.Lock()
+	if subscriptions.subs == nil {
+		subscriptions.subs = make(map[*subscription]struct{})
+	}
+	subscriptions.subs[sub] = struct{}{}
+	latest := subscriptions.latest
+	subscriptions.Unlock()
+
+	if latest != nil {
+		fn(latest)
+	}
+	context.AfterFunc(ctx, func() {
+		subscriptions.Lock()
+		defer subscriptions.Unlock()
+		delete(subscriptions.subs, sub)
+	})
+}
+
+func publish(cfg *Config, changed []string) {
+	subscriptions.Lock()
+	subscriptions.latest = cfg
+	var notify []*subscription
+	for sub := range subscriptions.subs {
+		if sub.ctx.Err() != nil {
+			continue
+		}
+		for _, name := range changed {
+			if strings.HasPrefix(name, sub.prefix) {
+				notify = append(notify, sub)
+				break
+			}
+		}
+	}
+	subscriptions.Unlock()
+
+	for _, sub := range notify {
+		sub.fn(cfg)
+	}
+}
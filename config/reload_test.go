@@ -0,0 +1,99 @@
+package config
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func resetSubscriptions(t *testing.T) {
+	t.Cleanup(func() {
+		subscriptions.Lock()
+		defer subscriptions.Unlock()
+		subscriptions.subs = nil
+		subscriptions.latest = nil
+	})
+}
+
+func TestReloadAppliesReloadableSettings(t *testing.T) {
+	resetSubscriptions(t)
+	file := writeFile(t, "flock.yaml", "log:\n  level: info\nserver:\n  port: 8000\n")
+	opts := Options{File: file, Environ: []string{}}
+	cfg, _, err := LoadWithOptions(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewReloader(cfg, opts)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var logCalls, rateCalls int
+	Subscribe(ctx, "log.", func(c *Config) {
+		logCalls++
+		if c.Log.Level != "debug" {
+			t.Errorf("subscriber got log.level %q, want debug", c.Log.Level)
+		}
+	})
+	Subscribe(ctx, "rate_limit.", func(*Config) { rateCalls++ })
+
+	content := "log:\n  level: debug\nserver:\n  port: 8001\n"
+	if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	changes, err := r.Reload()
+	if err != nil {
+		t.Fatalf("Reload() error: %v", err)
+	}
+	want := []Change{
+		{Setting: "server.port", Old: "8000", New: "8001", Applied: false},
+		{Setting: "log.level", Old: "info", New: "debug", Applied: true},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Fatalf("changes = %+v, want %+v", changes, want)
+	}
+	if cur := r.Current(); cur.Log.Level != "debug" || cur.Server.Port != 8000 {
+		t.Errorf("current config has log.level %q and server.port %d, want debug and 8000", cur.Log.Level, cur.Server.Port)
+	}
+	if logCalls != 1 || rateCalls != 0 {
+		t.Errorf("subscribers called %d and %d times, want 1 and 0", logCalls, rateCalls)
+	}
+
+	late := 0
+	Subscribe(ctx, "log.", func(*Config) { late++ })
+	if late != 1 {
+		t.Errorf("a subscriber added after a reload should be called at once")
+	}
+
+	cancel()
+	content = "log:\n  level: warn\nserver:\n  port: 8001\n"
+	if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if logCalls != 1 {
+		t.Errorf("subscriber called after its context was cancelled")
+	}
+}
+
+func TestReloadRejectsInvalidConfig(t *testing.T) {
+	resetSubscriptions(t)
+	file := writeFile(t, "flock.yaml", "log:\n  level: info\n")
+	opts := Options{File: file, Environ: []string{}}
+	cfg, _, err := LoadWithOptions(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewReloader(cfg, opts)
+
+	if err := os.WriteFile(file, []byte("log:\n  level: loud\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reload(); err == nil {
+		t.Fatal("Reload() should reject an invalid level")
+	}
+	if r.Current() != cfg {
+		t.Fatal("a failed reload should keep the current configuration")
+	}
+}
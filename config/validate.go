@@ -0,0 +1,264 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type Problem struct {
+	Env     string
+	Message string
+}
+
+type ValidationError struct {
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "invalid configuration (%d problems):", len(e.Problems))
+	for _, p := range e.Problems {
+		fmt.Fprintf(&b, "\n  %s: %s", p.Env, p.Message)
+	}
+	return b.String()
+}
+
+type validator struct {
+	env      map[string]string
+	problems []Problem
+}
+
+func (v *validator) check(ok bool, setting, format string, args ...any) {
+	if !ok {
+		v.problems = append(v.problems, Problem{Env: v.env[setting], Message: fmt.Sprintf(format, args...)})
+	}
+}
+
+func (v *validator) port(setting string, port int, allowZero bool) {
+	min := 1
+	if allowZero {
+		min = 0
+	}
+	v.check(port >= min && port <= 65535, setting, "must be between %d and 65535, got %d", min, port)
+}
+
+func (v *validator) oneOf(setting, value string, valid ...string) {
+	v.check(slices.Contains(valid, value), setting, "must be one of %s, got %q", strings.Join(valid, ", "), value)
+}
+
+func (v *validator) positive(setting string, d time.Duration) {
+	v.check(d > 0, setting, "must be positive, got %s", d)
+}
+
+// Validate reports all problems at once, at startup rather than first use.
+func (c *Config) Validate() error {
+	v := &validator{env: make(map[string]string)}
+	for _, s := range Settings() {
+		v.env[s.Name] = s.Env
+	}
+
+	v.check(c.Server.Host == "" || net.ParseIP(c.Server.Host) != nil || validHostname(c.Server.Host),
+		"server.host", "must be an IP address or hostname, got %q", c.Server.Host)
+	v.port("server.port", c.Server.Port, false)
+	v.port("server.grpc_port", c.Server.GRPCPort, false)
+	v.port("server.admin_port", c.Server.AdminPort, true)
+	v.port("events.port", c.Events.Port, false)
+	ports := map[int]string{}
+	for _, p := range []struct {
+		setting string
+		port    int
+	}{
+		{"server.port", c.Server.Port},
+		{"server.grpc_port", c.Server.GRPCPort},
+		{"server.admin_port", c.Server.AdminPort},
+		{"events.port", c.Events.Port},
+	} {
+		if p.port == 0 {
+			continue
+		}
+		if other, ok := ports[p.port]; ok {
+			v.check(false, p.setting, "port %d is already used by %s", p.port, v.env[other])
+			continue
+		}
+		ports[p.port] = p.setting
+	}
+
+	v.check((c.Server.TLSCert == "") == (c.Server.TLSKey == ""), "server.tls_key",
+		"%s and %s must be set together", v.env["server.tls_cert"], v.env["server.tls_key"])
+	v.oneOf("server.tls_min_version", c.Server.TLSMinVersion, "1.2", "1.3")
+	v.check(c.Server.TLSClientCA == "" || c.Server.TLSCert != "", "server.tls_client_ca",
+		"requires %s", v.env["server.tls_cert"])
+	v.oneOf("server.tls_client_auth", c.Server.TLSClientAuth, "required", "optional")
+
+	if _, err := pgx.ParseConfig(c.Postgres.DSN); err != nil {
+		v.check(false, "postgres.dsn", "cannot be parsed: %v", err)
+	}
+	if c.Postgres.MigrationDSN != "" {
+		if _, err := pgx.ParseConfig(c.Postgres.MigrationDSN); err != nil {
+			v.check(false, "postgres.migration_dsn", "cannot be parsed: %v", err)
+		}
+	}
+	v.check(c.Postgres.SchemaWait >= 0, "postgres.schema_wait", "must not be negative, got %s", c.Postgres.SchemaWait)
+	v.check(c.Postgres.MaxConns >= 0, "postgres.max_conns", "must not be negative, got %d", c.Postgres.MaxConns)
+	v.check(c.Postgres.MinConns >= 0 && (c.Postgres.MaxConns == 0 || c.Postgres.MinConns <= c.Postgres.MaxConns),
+		"postgres.min_conns", "must be between 0 and %s, got %d", v.env["postgres.max_conns"], c.Postgres.MinConns)
+	v.positive("postgres.max_conn_lifetime", c.Postgres.MaxConnLifetime)
+	v.check(c.Postgres.MaxConnLifetimeJitter >= 0, "postgres.max_conn_lifetime_jitter", "must not be negative, got %s", c.Postgres.MaxConnLifetimeJitter)
+	v.positive("postgres.max_conn_idle_time", c.Postgres.MaxConnIdleTime)
+	v.positive("postgres.health_check_period", c.Postgres.HealthCheckPeriod)
+	for i, dsn := range c.Postgres.ReplicaDSNs {
+		if _, err := pgx.ParseConfig(dsn); err != nil {
+			v.check(false, "postgres.replica_dsns", "replica %d cannot be parsed: %v", i+1, err)
+		}
+	}
+	if len(c.Postgres.ReplicaDSNs) > 0 {
+		v.positive("postgres.max_replica_lag", c.Postgres.MaxReplicaLag)
+		v.positive("postgres.replica_check_interval", c.Postgres.ReplicaCheckInterval)
+	}
+
+	levels := []string{"debug", "info", "warn", "error"}
+	formats := []string{"json", "text", "logfmt", "pretty", "gcp", "ecs"}
+	v.oneOf("log.level", c.Log.Level, levels...)
+	v.oneOf("log.format", c.Log.Format, formats...)
+	for _, component := range sortedKeys(c.Log.Levels) {
+		lvl := c.Log.Levels[component]
+		v.check(slices.Contains(levels, lvl), "log.levels",
+			"component %s: level must be one of %s, got %q", component, strings.Join(levels, ", "), lvl)
+	}
+	v.check(c.Log.Stdout || c.Log.File != "", "log.stdout", "cannot be false unless %s is set", v.env["log.file"])
+	if c.Log.StdoutLevel != "" {
+		v.oneOf("log.stdout_level", c.Log.StdoutLevel, levels...)
+	}
+	if c.Log.File != "" {
+		v.oneOf("log.file_format", c.Log.FileFormat, formats...)
+		if c.Log.FileLevel != "" {
+			v.oneOf("log.file_level", c.Log.FileLevel, levels...)
+		}
+		v.check(c.Log.FileMaxSizeMB >= 0, "log.file_max_size_mb", "must not be negative, got %d", c.Log.FileMaxSizeMB)
+		v.check(c.Log.FileMaxBackups >= 0, "log.file_max_backups", "must not be negative, got %d", c.Log.FileMaxBackups)
+	}
+
+	if c.Log.SampleFirst != 0 {
+		v.check(c.Log.SampleFirst > 0, "log.sample_first", "must not be negative, got %d", c.Log.SampleFirst)
+		v.check(c.Log.SampleThereafter >= 0, "log.sample_thereafter", "must not be negative, got %d", c.Log.SampleThereafter)
+		v.positive("log.sample_interval", c.Log.SampleInterval)
+	}
+	for _, route := range c.Log.AccessSkip {
+		v.check(strings.HasPrefix(route, "/"), "log.access_skip", "route %q must start with /", route)
+	}
+
+	for _, pattern := range c.Log.RedactKeys {
+		_, err := path.Match(pattern, "")
+		v.check(err == nil, "log.redact_keys", "invalid pattern %q", pattern)
+	}
+
+	v.positive("events.heartbeat_interval", c.Events.HeartbeatInterval)
+	v.positive("events.retention", c.Events.Retention)
+	v.check(c.Events.BufferSize > 0, "events.buffer_size", "must be positive, got %d", c.Events.BufferSize)
+
+	v.positive("webhooks.poll_interval", c.Webhooks.PollInterval)
+	v.positive("webhooks.timeout", c.Webhooks.Timeout)
+	v.check(c.Webhooks.Concurrency > 0, "webhooks.concurrency", "must be positive, got %d", c.Webhooks.Concurrency)
+	v.check(c.Webhooks.MaxAttempts > 0, "webhooks.max_attempts", "must be positive, got %d", c.Webhooks.MaxAttempts)
+	v.positive("webhooks.retry_base", c.Webhooks.RetryBase)
+	v.check(c.Webhooks.RetryMax >= c.Webhooks.RetryBase, "webhooks.retry_max",
+		"must not be less than %s (%s), got %s", v.env["webhooks.retry_base"], c.Webhooks.RetryBase, c.Webhooks.RetryMax)
+	v.check(c.Webhooks.DisableAfter >= 0, "webhooks.disable_after", "must not be negative, got %d", c.Webhooks.DisableAfter)
+
+	v.oneOf("tracing.exporter", c.Tracing.Exporter, "none", "otlp", "stdout", "file")
+	v.check(c.Tracing.Exporter != "file" || c.Tracing.File != "", "tracing.file", "is required when the exporter is file")
+	v.check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing.sample_ratio",
+		"must be between 0 and 1, got %g", c.Tracing.SampleRatio)
+
+	if c.RateLimit.Enabled {
+		v.oneOf("rate_limit.store", c.RateLimit.Store, "memory", "postgres")
+		v.oneOf("rate_limit.key_by", c.RateLimit.KeyBy, "key", "org", "device")
+		for _, name := range sortedKeys(c.RateLimit.Tiers) {
+			_, _, _, err := ParseRate(c.RateLimit.Tiers[name])
+			v.check(err == nil, "rate_limit.tiers", "tier %s: %v", name, err)
+		}
+		_, ok := c.RateLimit.Tiers[c.RateLimit.DefaultTier]
+		v.check(ok, "rate_limit.default_tier", "tier %q is not defined in %s", c.RateLimit.DefaultTier, v.env["rate_limit.tiers"])
+		for _, org := range sortedKeys(c.RateLimit.OrgTiers) {
+			tier := c.RateLimit.OrgTiers[org]
+			_, ok := c.RateLimit.Tiers[tier]
+			v.check(ok, "rate_limit.org_tiers", "organization %s uses undefined tier %q", org, tier)
+		}
+	}
+
+	for _, name := range sortedKeys(c.Supervisor.Restart) {
+		policy := c.Supervisor.Restart[name]
+		v.check(slices.Contains([]string{"never", "on-failure", "always"}, policy), "supervisor.restart",
+			"target %s: restart policy must be one of never, on-failure, always, got %q", name, policy)
+	}
+	v.positive("supervisor.backoff", c.Supervisor.Backoff)
+	v.check(c.Supervisor.MaxBackoff >= c.Supervisor.Backoff, "supervisor.max_backoff",
+		"must not be less than %s (%s), got %s", v.env["supervisor.backoff"], c.Supervisor.Backoff, c.Supervisor.MaxBackoff)
+	v.check(c.Supervisor.MaxRestarts >= 0, "supervisor.max_restarts", "must not be negative, got %d", c.Supervisor.MaxRestarts)
+	v.check(c.Supervisor.StartTimeout >= 0, "supervisor.start_timeout", "must not be negative, got %s", c.Supervisor.StartTimeout)
+	v.positive("supervisor.shutdown_timeout", c.Supervisor.ShutdownTimeout)
+	v.check(c.Supervisor.DrainDelay >= 0, "supervisor.drain_delay", "must not be negative, got %s", c.Supervisor.DrainDelay)
+
+	if len(v.problems) > 0 {
+		return &ValidationError{Problems: v.problems}
+	}
+	return nil
+}
+
+func validHostname(host string) bool {
+	for _, label := range strings.Split(host, ".") {
+		if label == "" || len(label) > 63 || strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-") {
+			return false
+		}
+		for _, r := range label {
+			if !(r == '-' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// ParseRate reads "<requests>/<duration>[:<burst>]", e.g. "600/1m:100".
+func ParseRate(s string) (count int, period time.Duration, burst int, err error) {
+	spec, burstStr, hasBurst := strings.Cut(s, ":")
+	countStr, periodStr, ok := strings.Cut(spec, "/")
+	if !ok {
+		return 0, 0, 0, fmt.Errorf("rate limit %q must look like 600/1m:100", s)
+	}
+	count, err = strconv.Atoi(countStr)
+	if err != nil || count < 1 {
+		return 0, 0, 0, fmt.Errorf("rate limit %q: request count must be a positive integer", s)
+	}
+	if periodStr == "s" || periodStr == "m" || periodStr == "h" {
+		periodStr = "1" + periodStr
+	}
+	period, err = time.ParseDuration(periodStr)
+	if err != nil || period <= 0 {
+		return 0, 0, 0, fmt.Errorf("rate limit %q: invalid period %q", s, periodStr)
+	}
+	burst = count
+	if hasBurst {
+		burst, err = strconv.Atoi(burstStr)
+		if err != nil || burst < 1 {
+			return 0, 0, 0, fmt.Errorf("rate limit %q: burst must be a positive integer", s)
+		}
+	}
+	return count, period, burst, nil
+}
@@ -0,0 +1,111 @@
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateDefaults(t *testing.T) {
+	cfg, _, err := LoadWithOptions(Options{Environ: []string{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("default configuration is invalid: %v", err)
+	}
+}
+
+func TestValidateReportsEveryProblem(t *testing.T) {
+	cfg, _, err := LoadWithOptions(Options{Environ: []string{
+		"FLOCK_SERVER_PORT=-1",
+		"FLOCK_SERVER_GRPC_PORT=8081",
+		"FLOCK_POSTGRES_DSN=postgres://flock@localhost:port/flock",
+		"FLOCK_LOG_LEVEL=verbose",
+		"FLOCK_WEBHOOKS_RETRY_MAX=1s",
+		"FLOCK_TRACING_EXPORTER=file",
+		"FLOCK_RATE_LIMIT_TIERS=default=fast",
+		"FLOCK_RATE_LIMIT_ORG_TIERS=acme=gold",
+		"FLOCK_SUPERVISOR_RESTART=api=sometimes",
+		"FLOCK_SUPERVISOR_SHUTDOWN_TIMEOUT=0s",
+		"FLOCK_LOG_SAMPLE_FIRST=10",
+		"FLOCK_LOG_SAMPLE_INTERVAL=0s",
+		"FLOCK_LOG_ACCESS_SKIP=healthz",
+		"FLOCK_POSTGRES_SCHEMA_WAIT=-1s",
+		"FLOCK_POSTGRES_MAX_CONNS=4",
+		"FLOCK_POSTGRES_MIN_CONNS=8",
+		"FLOCK_POSTGRES_REPLICA_DSNS=postgres://flock@replica:port/flock",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var verr *ValidationError
+	if err := cfg.Validate(); !errors.As(err, &verr) {
+		t.Fatalf("Validate() = %v, want a ValidationError", err)
+	}
+	got := map[string]string{}
+	for _, p := range verr.Problems {
+		got[p.Env] = p.Message
+	}
+	want := map[string]string{
+		"FLOCK_SERVER_PORT":                 "between 1 and 65535",
+		"FLOCK_EVENTS_PORT":                 "already used by FLOCK_SERVER_GRPC_PORT",
+		"FLOCK_POSTGRES_DSN":                "cannot be parsed",
+		"FLOCK_LOG_LEVEL":                   `got "verbose"`,
+		"FLOCK_WEBHOOKS_RETRY_MAX":          "less than FLOCK_WEBHOOKS_RETRY_BASE",
+		"FLOCK_TRACING_FILE":                "required",
+		"FLOCK_RATE_LIMIT_TIERS":            "tier default",
+		"FLOCK_RATE_LIMIT_ORG_TIERS":        `undefined tier "gold"`,
+		"FLOCK_SUPERVISOR_RESTART":          "target api",
+		"FLOCK_SUPERVISOR_SHUTDOWN_TIMEOUT": "must be positive",
+		"FLOCK_LOG_SAMPLE_INTERVAL":         "must be positive",
+		"FLOCK_LOG_ACCESS_SKIP":             "must start with /",
+		"FLOCK_POSTGRES_SCHEMA_WAIT":        "must not be negative",
+		"FLOCK_POSTGRES_MIN_CONNS":          "between 0 and FLOCK_POSTGRES_MAX_CONNS",
+		"FLOCK_POSTGRES_REPLICA_DSNS":       "replica 1 cannot be parsed",
+	}
+	for env, substr := range want {
+		if !strings.Contains(got[env], substr) {
+			t.Errorf("problem for %s = %q, want it to mention %q", env, got[env], substr)
+		}
+	}
+	if len(verr.Problems) != len(want) {
+		t.Errorf("got %d problems, want %d: %v", len(verr.Problems), len(want), verr)
+	}
+}
+
+func TestValidateAllowsDisabledAdminPort(t *testing.T) {
+	cfg, _, err := LoadWithOptions(Options{Environ: []string{"FLOCK_SERVER_ADMIN_PORT=0"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate() error: %v", err)
+	}
+}
+
+func TestParseRate(t *testing.T) {
+	for _, s := range []string{"", "10", "0/1m", "10/-1m", "10/1m:0", "x/1m"} {
+		if _, _, _, err := ParseRate(s); err == nil {
+			t.Errorf("ParseRate(%q) succeeded, want error", s)
+		}
+	}
+}
+
+func TestValidateTLS(t *testing.T) {
+	cfg, _, err := LoadWithOptions(Options{Environ: []string{
+		"FLOCK_SERVER_TLS_CLIENT_CA=/etc/flock/ca.pem",
+		"FLOCK_SERVER_TLS_KEY=/etc/flock/tls.key",
+		"FLOCK_SERVER_TLS_MIN_VERSION=1.0",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cfg.Validate()
+	for _, env := range []string{"FLOCK_SERVER_TLS_KEY", "FLOCK_SERVER_TLS_MIN_VERSION", "FLOCK_SERVER_TLS_CLIENT_CA"} {
+		if err == nil || !strings.Contains(err.Error(), env+":") {
+			t.Errorf("Validate() = %v, want a problem for %s", err, env)
+		}
+	}
+}
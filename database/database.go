@@ -4,24 +4,62 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/jackc/pgx/v5/multitracer"
 	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/flockiot/flock-api/config"
+	"github.com/flockiot/flock-api/tracing"
 )
 
 func Connect(ctx context.Context, dsn string) (*pgxpool.Pool, error) {
-	cfg, err := pgxpool.ParseConfig(dsn)
-	if err != nil {
-		return nil, fmt.Errorf("parsing database config: %w", err)
-	}
+	return connect(ctx, dsn, config.PostgresConfig{})
+}
+
+func Open(ctx context.Context, cfg config.PostgresConfig) (*pgxpool.Pool, error) {
+	return connect(ctx, cfg.DSN, cfg)
+}
 
-	pool, err := pgxpool.NewWithConfig(ctx, cfg)
+func connect(ctx context.Context, dsn string, cfg config.PostgresConfig) (*pgxpool.Pool, error) {
+	pool, err := newPool(ctx, dsn, cfg)
 	if err != nil {
-		return nil, fmt.Errorf("creating connection pool: %w", err)
+		return nil, err
 	}
-
 	if err := pool.Ping(ctx); err != nil {
 		pool.Close()
 		return nil, fmt.Errorf("pinging database: %w", err)
 	}
+	return pool, nil
+}
+
+// Zero settings in cfg keep pgxpool's defaults.
+func newPool(ctx context.Context, dsn string, cfg config.PostgresConfig) (*pgxpool.Pool, error) {
+	poolCfg, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("parsing database config: %w", err)
+	}
+	poolCfg.ConnConfig.Tracer = multitracer.New(tracing.QueryTracer{}, queryLogger{})
+	if cfg.MaxConns > 0 {
+		poolCfg.MaxConns = int32(cfg.MaxConns)
+	}
+	if cfg.MinConns > 0 {
+		poolCfg.MinConns = int32(cfg.MinConns)
+	}
+	if cfg.MaxConnLifetime > 0 {
+		poolCfg.MaxConnLifetime = cfg.MaxConnLifetime
+	}
+	if cfg.MaxConnLifetimeJitter > 0 {
+		poolCfg.MaxConnLifetimeJitter = cfg.MaxConnLifetimeJitter
+	}
+	if cfg.MaxConnIdleTime > 0 {
+		poolCfg.MaxConnIdleTime = cfg.MaxConnIdleTime
+	}
+	if cfg.HealthCheckPeriod > 0 {
+		poolCfg.HealthCheckPeriod = cfg.HealthCheckPeriod
+	}
 
+	pool, err := pgxpool.NewWithConfig(ctx, poolCfg)
+	if err != nil {
+		return nil, fmt.Errorf("creating connection pool: %w", err)
+	}
 	return pool, nil
 }
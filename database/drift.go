@@ -0,0 +1,165 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Definitions are rendered with search_path set to the schema, so they are
+// not qualified and two schemas can be compared.
+const snapshotQuery = `
+SELECT 'table ' || c.relname,
+       CASE c.relpersistence WHEN 'u' THEN 'UNLOGGED TABLE' ELSE 'TABLE' END
+FROM pg_class c
+JOIN pg_namespace n ON n.oid = c.relnamespace
+WHERE n.nspname = $1 AND c.relkind IN ('r', 'p') AND c.relname <> 'schema_migrations'
+UNION ALL
+SELECT 'column ' || c.relname || '.' || a.attname,
+       format_type(a.atttypid, a.atttypmod)
+         || CASE WHEN a.attnotnull THEN ' NOT NULL' ELSE '' END
+         || CASE a.attidentity WHEN 'a' THEN ' GENERATED ALWAYS AS IDENTITY'
+                               WHEN 'd' THEN ' GENERATED BY DEFAULT AS IDENTITY'
+                               ELSE '' END
+         || coalesce(' DEFAULT ' || pg_get_expr(d.adbin, d.adrelid), '')
+FROM pg_attribute a
+JOIN pg_class c ON c.oid = a.attrelid
+JOIN pg_namespace n ON n.oid = c.relnamespace
+LEFT JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+WHERE n.nspname = $1 AND c.relkind IN ('r', 'p') AND c.relname <> 'schema_migrations'
+  AND a.attnum > 0 AND NOT a.attisdropped
+UNION ALL
+SELECT 'index ' || i.relname, pg_get_indexdef(i.oid)
+FROM pg_index x
+JOIN pg_class i ON i.oid = x.indexrelid
+JOIN pg_class c ON c.oid = x.indrelid
+JOIN pg_namespace n ON n.oid = i.relnamespace
+WHERE n.nspname = $1 AND c.relname <> 'schema_migrations'
+UNION ALL
+SELECT 'constraint ' || c.relname || '.' || con.conname, pg_get_constraintdef(con.oid)
+FROM pg_constraint con
+JOIN pg_class c ON c.oid = con.conrelid
+JOIN pg_namespace n ON n.oid = c.relnamespace
+WHERE n.nspname = $1 AND c.relname <> 'schema_migrations'
+UNION ALL
+SELECT 'trigger ' || c.relname || '.' || t.tgname, pg_get_triggerdef(t.oid)
+FROM pg_trigger t
+JOIN pg_class c ON c.oid = t.tgrelid
+JOIN pg_namespace n ON n.oid = c.relnamespace
+WHERE n.nspname = $1 AND NOT t.tgisinternal
+UNION ALL
+SELECT 'function ' || p.proname || '(' || pg_get_function_identity_arguments(p.oid) || ')',
+       pg_get_function_result(p.oid) || ' ' || md5(p.prosrc)
+FROM pg_proc p
+JOIN pg_namespace n ON n.oid = p.pronamespace
+WHERE n.nspname = $1
+  AND NOT EXISTS (SELECT 1 FROM pg_depend e WHERE e.objid = p.oid AND e.deptype = 'e')`
+
+func snapshot(ctx context.Context, pool *pgxpool.Pool, schema string) (map[string]string, error) {
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("reading schema %s: %w", schema, err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, "SELECT set_config('search_path', $1, true)", pgx.Identifier{schema}.Sanitize()); err != nil {
+		return nil, fmt.Errorf("reading schema %s: %w", schema, err)
+	}
+	rows, err := tx.Query(ctx, snapshotQuery, schema)
+	if err != nil {
+		return nil, fmt.Errorf("reading schema %s: %w", schema, err)
+	}
+	defs := map[string]string{}
+	var name, def string
+	_, err = pgx.ForEachRow(rows, []any{&name, &def}, func() error {
+		defs[name] = def
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading schema %s: %w", schema, err)
+	}
+	return defs, nil
+}
+
+func diffSchemas(want, got map[string]string) []string {
+	var diffs []string
+	for name, w := range want {
+		g, ok := got[name]
+		switch {
+		case !ok:
+			diffs = append(diffs, fmt.Sprintf("missing %s: %s", name, w))
+		case g != w:
+			diffs = append(diffs, fmt.Sprintf("changed %s: want %s, got %s", name, w, g))
+		}
+	}
+	for name, g := range got {
+		if _, ok := want[name]; !ok {
+			diffs = append(diffs, fmt.Sprintf("unexpected %s: %s", name, g))
+		}
+	}
+	slices.Sort(diffs)
+	return diffs
+}
+
+// Drift creates a scratch schema, so pass the migration DSN.
+func Drift(ctx context.Context, dsn string) ([]string, error) {
+	pool, err := Connect(ctx, dsn)
+	if err != nil {
+		return nil, err
+	}
+	defer pool.Close()
+
+	var live string
+	if err := pool.QueryRow(ctx, "SELECT current_schema()").Scan(&live); err != nil {
+		return nil, fmt.Errorf("reading current schema: %w", err)
+	}
+
+	scratch := fmt.Sprintf("flock_drift_%d", time.Now().UnixNano())
+	if _, err := pool.Exec(ctx, "CREATE SCHEMA "+pgx.Identifier{scratch}.Sanitize()); err != nil {
+		return nil, fmt.Errorf("creating scratch schema: %w", err)
+	}
+	defer func() {
+		// Drop the scratch schema even if ctx was cancelled.
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+		defer cancel()
+		if _, err := pool.Exec(ctx, "DROP SCHEMA "+pgx.Identifier{scratch}.Sanitize()+" CASCADE"); err != nil {
+			slog.Warn("dropping scratch schema failed", "schema", scratch, "error", err)
+		}
+	}()
+
+	scratchDSN, err := withSearchPath(dsn, scratch)
+	if err != nil {
+		return nil, err
+	}
+	if err := Migrate(scratchDSN); err != nil {
+		return nil, fmt.Errorf("migrating scratch schema: %w", err)
+	}
+
+	want, err := snapshot(ctx, pool, scratch)
+	if err != nil {
+		return nil, err
+	}
+	got, err := snapshot(ctx, pool, live)
+	if err != nil {
+		return nil, err
+	}
+	return diffSchemas(want, got), nil
+}
+
+func withSearchPath(dsn, schema string) (string, error) {
+	u, err := url.Parse(dsn)
+	if err != nil || u.Scheme == "" {
+		return "", errors.New("drift detection needs a URL DSN such as postgres://user@host/db")
+	}
+	q := u.Query()
+	q.Set("search_path", schema)
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
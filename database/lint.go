@@ -0,0 +1,221 @@
+package database
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/golang-migrate/migrate/v4/source"
+)
+
+// A file can accept a finding with a "-- lint:ignore <rule>" comment.
+type LintIssue struct {
+	File    string
+	Rule    string
+	Message string
+}
+
+func (i LintIssue) String() string {
+	return fmt.Sprintf("%s: %s (%s)", i.File, i.Message, i.Rule)
+}
+
+var (
+	createTable = regexp.MustCompile(`(?i)^CREATE\s+(?:(?:GLOBAL|LOCAL)\s+)?(?:TEMP\w*\s+|UNLOGGED\s+)?TABLE\s+(?:IF\s+NOT\s+EXISTS\s+)?([\w."]+)`)
+	createIndex = regexp.MustCompile(`(?i)^CREATE\s+(?:UNIQUE\s+)?INDEX\s+(CONCURRENTLY\s+)?(?:IF\s+NOT\s+EXISTS\s+)?(?:[\w."]+\s+)?ON\s+(?:ONLY\s+)?([\w."]+)`)
+	concurrent  = regexp.MustCompile(`(?i)^(?:CREATE|DROP|REINDEX)\b.*\bCONCURRENTLY\b`)
+	alterTable  = regexp.MustCompile(`(?i)^ALTER\s+TABLE\s+(?:IF\s+EXISTS\s+)?(?:ONLY\s+)?([\w."]+)\s+(.*)$`)
+	addColumn   = regexp.MustCompile(`(?i)^ADD\s+(?:COLUMN\s+)?(?:IF\s+NOT\s+EXISTS\s+)?("[^"]+"|\w+)`)
+	notNull     = regexp.MustCompile(`(?i)\bNOT\s+NULL\b`)
+	hasDefault  = regexp.MustCompile(`(?i)\b(?:DEFAULT|GENERATED)\b`)
+	lintIgnore  = regexp.MustCompile(`--\s*lint:ignore\s+([\w-]+)`)
+	dollarTag   = regexp.MustCompile(`^\$\w*\$`)
+)
+
+func Lint() ([]LintIssue, error) {
+	sub, err := fs.Sub(migrations, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("reading migrations: %w", err)
+	}
+	return LintMigrations(sub)
+}
+
+// LintMigrations flags patterns that lock or fail on a populated table.
+func LintMigrations(fsys fs.FS) ([]LintIssue, error) {
+	names, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, fmt.Errorf("listing migrations: %w", err)
+	}
+
+	var issues []LintIssue
+	downs := map[uint]bool{}
+	var ups []*source.Migration
+	for _, name := range names {
+		m, err := source.Parse(name)
+		if err != nil {
+			return nil, fmt.Errorf("parsing migration %s: %w", name, err)
+		}
+		if m.Direction == source.Down {
+			downs[m.Version] = true
+			continue
+		}
+		ups = append(ups, m)
+
+		sql, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, fmt.Errorf("reading migration %s: %w", name, err)
+		}
+		issues = append(issues, lintFile(name, string(sql))...)
+	}
+	for _, m := range ups {
+		if !downs[m.Version] {
+			issues = append(issues, LintIssue{File: m.Raw, Rule: "missing-down", Message: "no down migration"})
+		}
+	}
+	slices.SortStableFunc(issues, func(a, b LintIssue) int { return strings.Compare(a.File, b.File) })
+	return issues, nil
+}
+
+func lintFile(name, sql string) []LintIssue {
+	ignored := map[string]bool{}
+	for _, m := range lintIgnore.FindAllStringSubmatch(sql, -1) {
+		ignored[m[1]] = true
+	}
+	var issues []LintIssue
+	report := func(rule, format string, args ...any) {
+		if !ignored[rule] {
+			issues = append(issues, LintIssue{File: path.Base(name), Rule: rule, Message: fmt.Sprintf(format, args...)})
+		}
+	}
+
+	statements := splitStatements(sql)
+	created := map[string]bool{}
+	for _, stmt := range statements {
+		if m := createTable.FindStringSubmatch(stmt); m != nil {
+			created[tableName(m[1])] = true
+			continue
+		}
+		if concurrent.MatchString(stmt) && len(statements) > 1 {
+			report("concurrent-not-alone", "%q must be the only statement in its file", abbreviate(stmt))
+		}
+		if m := createIndex.FindStringSubmatch(stmt); m != nil {
+			if table := tableName(m[2]); m[1] == "" && !created[table] {
+				report("index-not-concurrent", "index on existing table %s is not built CONCURRENTLY", table)
+			}
+			continue
+		}
+		if m := alterTable.FindStringSubmatch(stmt); m != nil {
+			table := tableName(m[1])
+			if created[table] {
+				continue
+			}
+			for _, action := range splitTopLevel(m[2], ',') {
+				col := addColumn.FindStringSubmatch(action)
+				if col != nil && notNull.MatchString(action) && !hasDefault.MatchString(action) {
+					report("not-null-without-default", "column %s.%s is added NOT NULL without a default", table, strings.Trim(col[1], `"`))
+				}
+			}
+		}
+	}
+	return issues
+}
+
+func tableName(s string) string {
+	if i := strings.LastIndex(s, "."); i >= 0 {
+		s = s[i+1:]
+	}
+	return strings.ToLower(strings.Trim(s, `"`))
+}
+
+func abbreviate(stmt string) string {
+	if len(stmt) > 60 {
+		return stmt[:57] + "..."
+	}
+	return stmt
+}
+
+// splitStatements ignores semicolons in comments, quotes and dollar-quoted
+// bodies.
+func splitStatements(sql string) []string {
+	var (
+		stmts []string
+		cur   strings.Builder
+	)
+	flush := func() {
+		if s := strings.Join(strings.Fields(cur.String()), " "); s != "" {
+			stmts = append(stmts, s)
+		}
+		cur.Reset()
+	}
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+		switch {
+		case c == '-' && strings.HasPrefix(sql[i:], "--"):
+			end := strings.IndexByte(sql[i:], '\n')
+			if end < 0 {
+				i = len(sql)
+			} else {
+				i += end
+			}
+			cur.WriteByte(' ')
+		case c == '/' && strings.HasPrefix(sql[i:], "/*"):
+			end := strings.Index(sql[i+2:], "*/")
+			if end < 0 {
+				i = len(sql)
+			} else {
+				i += end + 3
+			}
+			cur.WriteByte(' ')
+		case c == '\'' || c == '"':
+			end := strings.IndexByte(sql[i+1:], c)
+			if end < 0 {
+				cur.WriteString(sql[i:])
+				i = len(sql)
+				break
+			}
+			cur.WriteString(sql[i : i+end+2])
+			i += end + 1
+		case c == '$' && dollarTag.MatchString(sql[i:]):
+			tag := dollarTag.FindString(sql[i:])
+			end := strings.Index(sql[i+len(tag):], tag)
+			if end < 0 {
+				cur.WriteString(sql[i:])
+				i = len(sql)
+				break
+			}
+			n := 2*len(tag) + end
+			cur.WriteString(sql[i : i+n])
+			i += n - 1
+		case c == ';':
+			flush()
+		default:
+			cur.WriteByte(c)
+		}
+	}
+	flush()
+	return stmts
+}
+
+func splitTopLevel(s string, sep byte) []string {
+	var (
+		parts []string
+		depth int
+		start int
+	)
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case sep:
+			if depth == 0 {
+				parts = append(parts, strings.TrimSpace(s[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, strings.TrimSpace(s[start:]))
+}
@@ -0,0 +1,64 @@
+package database
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+// TestLintEmbeddedMigrations fails the build when a migration added to
+// database/migrations is unsafe to run against a populated database.
+func TestLintEmbeddedMigrations(t *testing.T) {
+	issues, err := Lint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, issue := range issues {
+		t.Error(issue)
+	}
+}
+
+func TestLintMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"000001_devices.up.sql": {Data: []byte(`
+CREATE TABLE devices (id uuid PRIMARY KEY, name text NOT NULL);
+CREATE INDEX idx_devices_name ON devices (name);`)},
+		"000001_devices.down.sql": {Data: []byte(`DROP TABLE devices;`)},
+		"000002_serial.up.sql": {Data: []byte(`
+ALTER TABLE devices ADD COLUMN serial text NOT NULL, ADD COLUMN kind text NOT NULL DEFAULT 'sensor';
+CREATE INDEX idx_devices_serial ON public.devices (serial);
+-- A semicolon in a comment; or a string ';' is not a statement boundary.
+CREATE FUNCTION f() RETURNS int AS $$ BEGIN RETURN 1; END; $$ LANGUAGE plpgsql;`)},
+		"000003_concurrent.up.sql": {Data: []byte(`
+CREATE INDEX CONCURRENTLY idx_devices_kind ON devices (kind);
+ALTER TABLE devices ADD COLUMN note text;`)},
+		"000003_concurrent.down.sql": {Data: []byte(`DROP INDEX idx_devices_kind;`)},
+		"000004_ignored.up.sql": {Data: []byte(`
+-- lint:ignore index-not-concurrent devices has a handful of rows
+CREATE INDEX idx_devices_note ON devices (note);`)},
+		"000004_ignored.down.sql": {Data: []byte(`DROP INDEX idx_devices_note;`)},
+	}
+
+	issues, err := LintMigrations(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	type key struct{ file, rule string }
+	got := map[key]bool{}
+	for _, issue := range issues {
+		got[key{issue.File, issue.Rule}] = true
+	}
+	want := []key{
+		{"000002_serial.up.sql", "not-null-without-default"},
+		{"000002_serial.up.sql", "index-not-concurrent"},
+		{"000002_serial.up.sql", "missing-down"},
+		{"000003_concurrent.up.sql", "concurrent-not-alone"},
+	}
+	for _, k := range want {
+		if !got[k] {
+			t.Errorf("missing %s issue in %s", k.rule, k.file)
+		}
+	}
+	if len(issues) != len(want) {
+		t.Errorf("got %d issues, want %d: %v", len(issues), len(want), issues)
+	}
+}
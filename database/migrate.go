@@ -1,34 +1,178 @@
 package database
 
 import (
+	"context"
 	"embed"
 	"errors"
 	"fmt"
+	"io/fs"
+	"log/slog"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/pgx/v5"
+	"github.com/golang-migrate/migrate/v4/source"
 	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 //go:embed migrations/*.sql
 var migrations embed.FS
 
-func Migrate(dsn string) error {
-	source, err := iofs.New(migrations, "migrations")
+type Migration struct {
+	Version uint
+	Name    string
+}
+
+func Migrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrations, "migrations")
 	if err != nil {
-		return fmt.Errorf("creating migration source: %w", err)
+		return nil, fmt.Errorf("reading migrations: %w", err)
+	}
+	var list []Migration
+	for _, e := range entries {
+		m, err := source.Parse(e.Name())
+		if err != nil {
+			return nil, fmt.Errorf("parsing migration %s: %w", e.Name(), err)
+		}
+		if m.Direction == source.Up {
+			list = append(list, Migration{Version: m.Version, Name: m.Identifier})
+		}
+	}
+	slices.SortFunc(list, func(a, b Migration) int { return int(a.Version) - int(b.Version) })
+	return list, nil
+}
+
+type Migrator struct {
+	m *migrate.Migrate
+}
+
+func NewMigrator(dsn string) (*Migrator, error) {
+	src, err := iofs.New(migrations, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("creating migration source: %w", err)
 	}
 
 	migrateDSN := strings.Replace(dsn, "postgres://", "pgx5://", 1)
-	m, err := migrate.NewWithSourceInstance("iofs", source, migrateDSN)
+	m, err := migrate.NewWithSourceInstance("iofs", src, migrateDSN)
+	if err != nil {
+		return nil, fmt.Errorf("creating migrate instance: %w", err)
+	}
+	return &Migrator{m: m}, nil
+}
+
+// A dirty schema must be repaired by hand before Force marks it clean.
+func (m *Migrator) Version() (version uint, dirty bool, err error) {
+	version, dirty, err = m.m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return 0, false, nil
+	}
 	if err != nil {
-		return fmt.Errorf("creating migrate instance: %w", err)
+		return 0, false, fmt.Errorf("reading schema version: %w", err)
 	}
+	return version, dirty, nil
+}
 
-	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+func (m *Migrator) Up() error {
+	if err := m.m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
 		return fmt.Errorf("running migrations: %w", err)
 	}
+	return nil
+}
 
+func (m *Migrator) Goto(version uint) error {
+	if err := m.m.Migrate(version); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("migrating to version %d: %w", version, err)
+	}
 	return nil
 }
+
+func (m *Migrator) Down(n int) error {
+	if err := m.m.Steps(-n); err != nil {
+		return fmt.Errorf("rolling back %d migrations: %w", n, err)
+	}
+	return nil
+}
+
+func (m *Migrator) Force(version uint) error {
+	if err := m.m.Force(int(version)); err != nil {
+		return fmt.Errorf("forcing version %d: %w", version, err)
+	}
+	return nil
+}
+
+func (m *Migrator) Close() error {
+	srcErr, dbErr := m.m.Close()
+	return errors.Join(srcErr, dbErr)
+}
+
+func Migrate(dsn string) error {
+	m, err := NewMigrator(dsn)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+	return m.Up()
+}
+
+const schemaPollInterval = 2 * time.Second
+
+var ErrSchemaBehind = errors.New("database schema is behind")
+
+// SchemaVersion works for roles without DDL privileges.
+func SchemaVersion(ctx context.Context, pool *pgxpool.Pool) (version uint, dirty bool, err error) {
+	var v int64
+	err = pool.QueryRow(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&v, &dirty)
+	var pgErr *pgconn.PgError
+	switch {
+	case errors.Is(err, pgx.ErrNoRows), errors.As(err, &pgErr) && pgErr.Code == "42P01":
+		return 0, false, nil
+	case err != nil:
+		return 0, false, fmt.Errorf("reading schema version: %w", err)
+	}
+	return uint(v), dirty, nil
+}
+
+// A newer schema is accepted, since during a rollout old replicas keep
+// running against the migrated database.
+func CheckSchema(ctx context.Context, pool *pgxpool.Pool) error {
+	list, err := Migrations()
+	if err != nil {
+		return err
+	}
+	var want uint
+	if len(list) > 0 {
+		want = list[len(list)-1].Version
+	}
+	version, dirty, err := SchemaVersion(ctx, pool)
+	if err != nil {
+		return err
+	}
+	switch {
+	case dirty:
+		return fmt.Errorf("%w: migration %d failed and left it dirty", ErrSchemaBehind, version)
+	case version < want:
+		return fmt.Errorf("%w: at version %d, need %d", ErrSchemaBehind, version, want)
+	}
+	return nil
+}
+
+func WaitForSchema(ctx context.Context, pool *pgxpool.Pool, wait time.Duration) error {
+	deadline := time.Now().Add(wait)
+	for {
+		err := CheckSchema(ctx, pool)
+		if err == nil || !errors.Is(err, ErrSchemaBehind) || time.Now().Add(schemaPollInterval).After(deadline) {
+			return err
+		}
+		slog.Info("waiting for database migrations", "error", err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(schemaPollInterval):
+		}
+	}
+}
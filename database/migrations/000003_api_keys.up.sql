@@ -0,0 +1,10 @@
+CREATE TABLE api_keys (
+    id              uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id uuid NOT NULL REFERENCES organizations (id),
+    name            text NOT NULL,
+    token_hash      bytea NOT NULL UNIQUE,
+    revoked_at      timestamptz,
+    created_at      timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_api_keys_organization_id ON api_keys (organization_id) WHERE revoked_at IS NULL;
@@ -0,0 +1,22 @@
+CREATE TABLE events (
+    id              bigint GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    type            text NOT NULL,
+    organization_id uuid NOT NULL,
+    fleet_id        uuid,
+    device_id       uuid,
+    data            jsonb NOT NULL DEFAULT '{}',
+    created_at      timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_events_organization_id ON events (organization_id, id);
+CREATE INDEX idx_events_created_at ON events (created_at);
+
+CREATE FUNCTION notify_event() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('flock_events', NEW.id::text);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER events_notify AFTER INSERT ON events
+    FOR EACH ROW EXECUTE FUNCTION notify_event();
@@ -0,0 +1,74 @@
+CREATE TABLE webhook_subscriptions (
+    id                   uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    organization_id      uuid NOT NULL REFERENCES organizations (id),
+    url                  text NOT NULL,
+    secret               text NOT NULL,
+    event_types          text[] NOT NULL DEFAULT '{}',
+    enabled              boolean NOT NULL DEFAULT true,
+    consecutive_failures integer NOT NULL DEFAULT 0,
+    disabled_at          timestamptz,
+    deleted_at           timestamptz,
+    created_at           timestamptz NOT NULL DEFAULT now(),
+    updated_at           timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_webhook_subscriptions_organization_id ON webhook_subscriptions (organization_id) WHERE deleted_at IS NULL;
+
+CREATE TABLE webhook_deliveries (
+    id               uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    subscription_id  uuid NOT NULL REFERENCES webhook_subscriptions (id),
+    event_id         bigint NOT NULL,
+    event_type       text NOT NULL,
+    payload          jsonb NOT NULL,
+    status           text NOT NULL DEFAULT 'pending',
+    attempts         integer NOT NULL DEFAULT 0,
+    next_attempt_at  timestamptz NOT NULL DEFAULT now(),
+    last_status_code integer,
+    last_error       text,
+    created_at       timestamptz NOT NULL DEFAULT now(),
+    updated_at       timestamptz NOT NULL DEFAULT now(),
+    CONSTRAINT webhook_deliveries_event_unique UNIQUE (subscription_id, event_id),
+    CONSTRAINT webhook_deliveries_status_check CHECK (status IN ('pending', 'succeeded', 'failed'))
+);
+
+CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX idx_webhook_deliveries_subscription_id ON webhook_deliveries (subscription_id, created_at);
+
+CREATE TABLE webhook_delivery_attempts (
+    id          bigint GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    delivery_id uuid NOT NULL REFERENCES webhook_deliveries (id),
+    status_code integer,
+    error       text,
+    duration_ms integer NOT NULL,
+    created_at  timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_webhook_delivery_attempts_delivery_id ON webhook_delivery_attempts (delivery_id, id);
+
+-- Fan every new event out to the matching subscriptions in the same
+-- transaction, so deliveries are neither lost nor duplicated across replicas.
+CREATE FUNCTION enqueue_webhook_deliveries() RETURNS trigger AS $$
+BEGIN
+    INSERT INTO webhook_deliveries (subscription_id, event_id, event_type, payload)
+    SELECT s.id, NEW.id, NEW.type,
+           jsonb_build_object(
+               'id', NEW.id,
+               'type', NEW.type,
+               'organization_id', NEW.organization_id,
+               'data', NEW.data,
+               'created_at', NEW.created_at
+           )
+           || CASE WHEN NEW.fleet_id IS NULL THEN '{}'::jsonb ELSE jsonb_build_object('fleet_id', NEW.fleet_id) END
+           || CASE WHEN NEW.device_id IS NULL THEN '{}'::jsonb ELSE jsonb_build_object('device_id', NEW.device_id) END
+    FROM webhook_subscriptions s
+    WHERE s.organization_id = NEW.organization_id
+      AND s.enabled
+      AND s.deleted_at IS NULL
+      AND (cardinality(s.event_types) = 0 OR NEW.type = ANY(s.event_types))
+    ON CONFLICT DO NOTHING;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER events_enqueue_webhooks AFTER INSERT ON events
+    FOR EACH ROW EXECUTE FUNCTION enqueue_webhook_deliveries();
@@ -0,0 +1,29 @@
+CREATE TABLE audit_events (
+    id              bigint GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    occurred_at     timestamptz NOT NULL DEFAULT now(),
+    actor_type      text NOT NULL,
+    actor_id        text NOT NULL DEFAULT '',
+    organization_id uuid,
+    action          text NOT NULL,
+    resource_type   text NOT NULL,
+    resource_id     text NOT NULL,
+    changes         jsonb NOT NULL DEFAULT '{}',
+    request_id      text NOT NULL DEFAULT '',
+    source_ip       text NOT NULL DEFAULT ''
+);
+
+CREATE INDEX idx_audit_events_organization_id ON audit_events (organization_id, id);
+CREATE INDEX idx_audit_events_resource ON audit_events (resource_type, resource_id);
+CREATE INDEX idx_audit_events_occurred_at ON audit_events (occurred_at);
+
+CREATE FUNCTION reject_audit_event_change() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_events_append_only BEFORE UPDATE OR DELETE ON audit_events
+    FOR EACH ROW EXECUTE FUNCTION reject_audit_event_change();
+
+CREATE TRIGGER audit_events_no_truncate BEFORE TRUNCATE ON audit_events
+    FOR EACH STATEMENT EXECUTE FUNCTION reject_audit_event_change();
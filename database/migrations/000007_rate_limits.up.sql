@@ -0,0 +1,9 @@
+-- Token buckets shared by every api replica. Rows are recreated on demand, so
+-- idle buckets can be deleted at any time.
+CREATE UNLOGGED TABLE rate_limit_buckets (
+    key        text PRIMARY KEY,
+    tokens     double precision NOT NULL,
+    updated_at timestamptz NOT NULL
+);
+
+CREATE INDEX idx_rate_limit_buckets_updated_at ON rate_limit_buckets (updated_at);
@@ -0,0 +1,118 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/flockiot/flock-api/config"
+)
+
+// A replica that has replayed up to the primary position $1 is not behind,
+// however long ago its last transaction was.
+const lagQuery = `
+SELECT CASE
+    WHEN NOT pg_is_in_recovery() THEN 0
+    WHEN pg_last_wal_replay_lsn() >= $1::pg_lsn THEN 0
+    ELSE extract(epoch FROM now() - pg_last_xact_replay_timestamp())::float8
+END`
+
+type Replicas struct {
+	primary  *pgxpool.Pool
+	replicas []*replica
+	maxLag   time.Duration
+	interval time.Duration
+	next     atomic.Uint64
+}
+
+type replica struct {
+	name    string
+	pool    *pgxpool.Pool
+	healthy atomic.Bool
+}
+
+// An unreachable replica is not an error; reads use the primary until it
+// catches up.
+func OpenReplicas(ctx context.Context, primary *pgxpool.Pool, cfg config.PostgresConfig) (*Replicas, error) {
+	r := &Replicas{primary: primary, maxLag: cfg.MaxReplicaLag, interval: cfg.ReplicaCheckInterval}
+	for _, dsn := range cfg.ReplicaDSNs {
+		pool, err := newPool(ctx, dsn, cfg)
+		if err != nil {
+			r.Close()
+			return nil, err
+		}
+		c := pool.Config().ConnConfig
+		r.replicas = append(r.replicas, &replica{name: fmt.Sprintf("%s:%d", c.Host, c.Port), pool: pool})
+	}
+	r.check(ctx)
+	return r, nil
+}
+
+func (r *Replicas) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.check(ctx)
+		}
+	}
+}
+
+func (r *Replicas) check(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, r.interval)
+	defer cancel()
+
+	var lsn string
+	if err := r.primary.QueryRow(ctx, "SELECT pg_current_wal_lsn()::text").Scan(&lsn); err != nil {
+		// Without the primary's position lag looks like an idle primary.
+		slog.Warn("reading primary WAL position failed", "error", err)
+		return
+	}
+	for _, rep := range r.replicas {
+		var lag *float64
+		err := rep.pool.QueryRow(ctx, lagQuery, lsn).Scan(&lag)
+		if err == nil && lag == nil {
+			err = errors.New("replica has not replayed any transaction yet")
+		}
+		healthy := err == nil && time.Duration(*lag*float64(time.Second)) <= r.maxLag
+		if rep.healthy.Swap(healthy) == healthy {
+			continue
+		}
+		switch {
+		case healthy:
+			slog.Info("read replica in use", "replica", rep.name)
+		case err != nil:
+			slog.Warn("read replica unavailable, reading from the primary", "replica", rep.name, "error", err)
+		default:
+			slog.Warn("read replica lagging, reading from the primary", "replica", rep.name,
+				"lag", time.Duration(*lag*float64(time.Second)).String(), "max_lag", r.maxLag.String())
+		}
+	}
+}
+
+// Reads that must see the last MaxReplicaLag of writes should use the
+// primary directly.
+func (r *Replicas) Reader() *pgxpool.Pool {
+	n := uint64(len(r.replicas))
+	start := r.next.Add(1)
+	for i := range n {
+		if rep := r.replicas[(start+i)%n]; rep.healthy.Load() {
+			return rep.pool
+		}
+	}
+	return r.primary
+}
+
+func (r *Replicas) Close() {
+	for _, rep := range r.replicas {
+		rep.pool.Close()
+	}
+}
@@ -0,0 +1,63 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/flockiot/flock-api/config"
+)
+
+// lazyPool returns a pool that never connects, since none of these tests
+// run a query.
+func lazyPool(t *testing.T, cfg config.PostgresConfig) *pgxpool.Pool {
+	t.Helper()
+	pool, err := newPool(context.Background(), "postgres://flock@localhost:1/flock", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+	return pool
+}
+
+func TestNewPoolAppliesSettings(t *testing.T) {
+	pool := lazyPool(t, config.PostgresConfig{MaxConns: 7, MaxConnIdleTime: time.Minute})
+	c := pool.Config()
+	if c.MaxConns != 7 || c.MaxConnIdleTime != time.Minute {
+		t.Fatalf("pool config = max %d, idle %s; want 7, 1m", c.MaxConns, c.MaxConnIdleTime)
+	}
+	if c.MaxConnLifetime != time.Hour {
+		t.Errorf("MaxConnLifetime = %s, want pgxpool's default of 1h", c.MaxConnLifetime)
+	}
+}
+
+func TestReaderFallsBackToPrimary(t *testing.T) {
+	primary := lazyPool(t, config.PostgresConfig{})
+	r := &Replicas{primary: primary}
+	for range 2 {
+		r.replicas = append(r.replicas, &replica{pool: lazyPool(t, config.PostgresConfig{})})
+	}
+
+	if got := r.Reader(); got != primary {
+		t.Fatal("Reader() should return the primary while no replica is healthy")
+	}
+
+	r.replicas[0].healthy.Store(true)
+	r.replicas[1].healthy.Store(true)
+	seen := map[*pgxpool.Pool]bool{}
+	for range 4 {
+		seen[r.Reader()] = true
+	}
+	if len(seen) != 2 || seen[primary] {
+		t.Fatalf("Reader() should take turns between the healthy replicas, got %d pools", len(seen))
+	}
+
+	r.replicas[0].healthy.Store(false)
+	for range 4 {
+		if got := r.Reader(); got != r.replicas[1].pool {
+			t.Fatal("Reader() should skip a lagging replica")
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package events
+
+import (
+	"sync"
+
+	"github.com/flockiot/flock-api/repository"
+)
+
+// Publishing never blocks: a subscriber whose buffer is full is dropped.
+type Broker struct {
+	mu         sync.Mutex
+	subs       map[*Subscription]struct{}
+	bufferSize int
+	cursor     int64
+}
+
+// Every event with an ID up to Cursor had been published, or never will be,
+// when the message was.
+type Message struct {
+	*repository.Event
+	Cursor int64
+}
+
+func NewBroker(bufferSize int) *Broker {
+	if bufferSize <= 0 {
+		bufferSize = 1
+	}
+	return &Broker{
+		subs:       make(map[*Subscription]struct{}),
+		bufferSize: bufferSize,
+	}
+}
+
+type Subscription struct {
+	broker  *Broker
+	filter  repository.EventFilter
+	ch      chan Message
+	dropped chan struct{}
+}
+
+func (b *Broker) Subscribe(filter repository.EventFilter) *Subscription {
+	s := &Subscription{
+		broker:  b,
+		filter:  filter,
+		ch:      make(chan Message, b.bufferSize),
+		dropped: make(chan struct{}),
+	}
+	b.mu.Lock()
+	b.subs[s] = struct{}{}
+	b.mu.Unlock()
+	return s
+}
+
+func (b *Broker) Publish(e *repository.Event, cursor int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.cursor = cursor
+	for s := range b.subs {
+		if !s.filter.Match(e) {
+			continue
+		}
+		select {
+		case s.ch <- Message{Event: e, Cursor: cursor}:
+		default:
+			delete(b.subs, s)
+			close(s.dropped)
+		}
+	}
+}
+
+func (b *Broker) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subs)
+}
+
+func (b *Broker) setCursor(cursor int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.cursor = cursor
+}
+
+func (b *Broker) Cursor() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.cursor
+}
+
+func (s *Subscription) Events() <-chan Message {
+	return s.ch
+}
+
+// Dropped is closed when the subscriber falls too far behind.
+func (s *Subscription) Dropped() <-chan struct{} {
+	return s.dropped
+}
+
+func (s *Subscription) Close() {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	delete(s.broker.subs, s)
+}
@@ -0,0 +1,65 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/flockiot/flock-api/repository"
+)
+
+func TestBrokerDeliversMatchingEvents(t *testing.T) {
+	b := NewBroker(4)
+	sub := b.Subscribe(repository.EventFilter{OrganizationID: "org-a"})
+	defer sub.Close()
+
+	b.Publish(&repository.Event{ID: 1, OrganizationID: "org-b"}, 1)
+	b.Publish(&repository.Event{ID: 2, OrganizationID: "org-a"}, 2)
+
+	select {
+	case e := <-sub.Events():
+		if e.ID != 2 {
+			t.Fatalf("expected event 2, got %d", e.ID)
+		}
+	default:
+		t.Fatal("expected an event to be delivered")
+	}
+	select {
+	case e := <-sub.Events():
+		t.Fatalf("unexpected event %d", e.ID)
+	default:
+	}
+}
+
+func TestBrokerDropsSlowSubscriber(t *testing.T) {
+	b := NewBroker(1)
+	slow := b.Subscribe(repository.EventFilter{})
+	fast := b.Subscribe(repository.EventFilter{})
+	defer fast.Close()
+
+	b.Publish(&repository.Event{ID: 1}, 1)
+	<-fast.Events()
+	b.Publish(&repository.Event{ID: 2}, 2)
+
+	select {
+	case <-slow.Dropped():
+	default:
+		t.Fatal("expected slow subscriber to be dropped")
+	}
+	select {
+	case <-fast.Dropped():
+		t.Fatal("fast subscriber should not be dropped")
+	default:
+	}
+	if b.Len() != 1 {
+		t.Fatalf("expected 1 subscriber, got %d", b.Len())
+	}
+}
+
+func TestSubscriptionClose(t *testing.T) {
+	b := NewBroker(1)
+	sub := b.Subscribe(repository.EventFilter{})
+	sub.Close()
+	if b.Len() != 0 {
+		t.Fatalf("expected 0 subscribers, got %d", b.Len())
+	}
+	b.Publish(&repository.Event{ID: 1}, 1)
+}
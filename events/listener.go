@@ -0,0 +1,169 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/flockiot/flock-api/repository"
+)
+
+const (
+	notifyChannel = "flock_events"
+	fetchBatch    = 500
+	retryDelay    = 5 * time.Second
+
+	// IDs are taken on insert, not commit, so a lower ID can show up after a
+	// higher one; IDs of rolled back inserts never do.
+	gapTimeout = 30 * time.Second
+	maxGaps    = 10000
+)
+
+type eventStore interface {
+	LatestID(ctx context.Context) (int64, error)
+	ListSince(ctx context.Context, filter repository.EventFilter, afterID int64, limit int) ([]*repository.Event, error)
+}
+
+// Notifications only wake the listener; rows are always read from the table
+// so nothing is lost across reconnects.
+type Listener struct {
+	pool   *pgxpool.Pool
+	events eventStore
+	broker *Broker
+	lastID int64
+	gaps   map[int64]time.Time // IDs below lastID not seen yet, and since when
+
+	listening atomic.Bool
+}
+
+func NewListener(pool *pgxpool.Pool, events *repository.EventRepository, broker *Broker) *Listener {
+	return &Listener{pool: pool, events: events, broker: broker, gaps: map[int64]time.Time{}}
+}
+
+func (l *Listener) Run(ctx context.Context) error {
+	lastID, err := l.events.LatestID(ctx)
+	if err != nil {
+		return err
+	}
+	l.lastID = lastID
+	l.broker.setCursor(lastID)
+
+	for {
+		err := l.listen(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		slog.Error("event listener failed, retrying", "error", err, "retry_in", retryDelay)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(retryDelay):
+		}
+	}
+}
+
+func (l *Listener) Check(_ context.Context) error {
+	if !l.listening.Load() {
+		return errors.New("event listener is not connected")
+	}
+	return nil
+}
+
+func (l *Listener) listen(ctx context.Context) error {
+	conn, err := l.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("acquiring connection: %w", err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, "LISTEN "+notifyChannel); err != nil {
+		return fmt.Errorf("listening for events: %w", err)
+	}
+	l.listening.Store(true)
+	defer l.listening.Store(false)
+	defer func() {
+		_, _ = conn.Exec(context.WithoutCancel(ctx), "UNLISTEN "+notifyChannel)
+	}()
+
+	for {
+		if err := l.dispatch(ctx); err != nil {
+			return err
+		}
+		if _, err := conn.Conn().WaitForNotification(ctx); err != nil {
+			return fmt.Errorf("waiting for notification: %w", err)
+		}
+	}
+}
+
+// dispatch reads from below the oldest gap so rows committing out of order
+// are still published, once.
+func (l *Listener) dispatch(ctx context.Context) error {
+	now := time.Now()
+	from := l.lastID
+	for id, since := range l.gaps {
+		if now.Sub(since) > gapTimeout {
+			delete(l.gaps, id)
+		} else if id <= from {
+			from = id - 1
+		}
+	}
+
+	for {
+		batch, err := l.events.ListSince(ctx, repository.EventFilter{}, from, fetchBatch)
+		if err != nil {
+			return err
+		}
+		for _, e := range batch {
+			if e.ID <= l.lastID {
+				if _, ok := l.gaps[e.ID]; !ok {
+					continue
+				}
+				delete(l.gaps, e.ID)
+			} else {
+				for id := max(l.lastID+1, e.ID-maxGaps); id < e.ID; id++ {
+					l.gaps[id] = now
+				}
+				l.lastID = e.ID
+			}
+			l.broker.Publish(e, l.cursor())
+		}
+		if len(batch) < fetchBatch {
+			return nil
+		}
+		from = batch[len(batch)-1].ID
+	}
+}
+
+// cursor is the highest ID below which no event is still expected.
+func (l *Listener) cursor() int64 {
+	c := l.lastID
+	for id := range l.gaps {
+		c = min(c, id-1)
+	}
+	return c
+}
+
+func Prune(ctx context.Context, events *repository.EventRepository, retention, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := events.DeleteBefore(ctx, time.Now().Add(-retention))
+			if err != nil {
+				slog.Error("pruning events failed", "error", err)
+				continue
+			}
+			if n > 0 {
+				slog.Info("pruned events", "count", n)
+			}
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package events
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/flockiot/flock-api/repository"
+)
+
+// fakeEvents holds the committed rows, in any order.
+type fakeEvents struct {
+	rows []*repository.Event
+}
+
+func (f *fakeEvents) LatestID(context.Context) (int64, error) {
+	return 0, nil
+}
+
+func (f *fakeEvents) ListSince(_ context.Context, _ repository.EventFilter, afterID int64, limit int) ([]*repository.Event, error) {
+	var out []*repository.Event
+	for _, e := range f.rows {
+		if e.ID > afterID {
+			out = append(out, e)
+		}
+	}
+	slices.SortFunc(out, func(a, b *repository.Event) int { return int(a.ID - b.ID) })
+	return out[:min(limit, len(out))], nil
+}
+
+// published returns the IDs and cursors of the messages sent to sub.
+func published(sub *Subscription) (ids, cursors []int64) {
+	for {
+		select {
+		case m := <-sub.Events():
+			ids = append(ids, m.ID)
+			cursors = append(cursors, m.Cursor)
+		default:
+			return ids, cursors
+		}
+	}
+}
+
+func TestListenerPublishesEventsCommittedOutOfOrder(t *testing.T) {
+	store := &fakeEvents{}
+	broker := NewBroker(16)
+	sub := broker.Subscribe(repository.EventFilter{})
+	defer sub.Close()
+	l := &Listener{events: store, broker: broker, gaps: map[int64]time.Time{}}
+
+	// 2 was inserted before 3 but commits after it.
+	store.rows = append(store.rows, &repository.Event{ID: 1}, &repository.Event{ID: 3})
+	if err := l.dispatch(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	store.rows = append(store.rows, &repository.Event{ID: 2}, &repository.Event{ID: 4})
+	if err := l.dispatch(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.dispatch(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ids, cursors := published(sub)
+	if want := []int64{1, 3, 2, 4}; !slices.Equal(ids, want) {
+		t.Fatalf("published %v, want %v", ids, want)
+	}
+	// Until 2 arrives, a client resuming after 3 would miss it.
+	if want := []int64{1, 1, 3, 4}; !slices.Equal(cursors, want) {
+		t.Fatalf("cursors %v, want %v", cursors, want)
+	}
+	if len(l.gaps) != 0 {
+		t.Fatalf("gaps = %v, want none", l.gaps)
+	}
+}
+
+func TestListenerForgetsOldGaps(t *testing.T) {
+	store := &fakeEvents{rows: []*repository.Event{{ID: 1}, {ID: 3}}}
+	l := &Listener{events: store, broker: NewBroker(16), gaps: map[int64]time.Time{}}
+	if err := l.dispatch(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := l.gaps[2]; !ok {
+		t.Fatalf("gaps = %v, want 2 to be waited for", l.gaps)
+	}
+
+	// 2 was rolled back.
+	l.gaps[2] = time.Now().Add(-2 * gapTimeout)
+	if err := l.dispatch(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(l.gaps) != 0 {
+		t.Fatalf("gaps = %v, want none", l.gaps)
+	}
+}
+
+func TestListenerCheck(t *testing.T) {
+	l := &Listener{}
+	if err := l.Check(context.Background()); err == nil {
+		t.Fatal("expected the check to fail without a LISTEN connection")
+	}
+	l.listening.Store(true)
+	if err := l.Check(context.Background()); err != nil {
+		t.Fatalf("expected the check to pass while listening: %v", err)
+	}
+}
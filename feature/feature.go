@@ -0,0 +1,20 @@
+package feature
+
+import (
+	"sync/atomic"
+)
+
+var enabled atomic.Pointer[map[string]bool]
+
+func Set(names []string) {
+	m := make(map[string]bool, len(names))
+	for _, name := range names {
+		m[name] = true
+	}
+	enabled.Store(&m)
+}
+
+func Enabled(name string) bool {
+	m := enabled.Load()
+	return m != nil && (*m)[name]
+}
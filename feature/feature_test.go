@@ -0,0 +1,17 @@
+package feature
+
+import "testing"
+
+func TestSet(t *testing.T) {
+	if Enabled("beta") {
+		t.Fatal("no flag should be enabled before Set")
+	}
+	Set([]string{"beta", "gamma"})
+	if !Enabled("beta") || !Enabled("gamma") || Enabled("delta") {
+		t.Fatal("Enabled does not match the flags given to Set")
+	}
+	Set(nil)
+	if Enabled("beta") {
+		t.Fatal("Set should replace the previous flags")
+	}
+}
@@ -0,0 +1,152 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/flockiot/flock-api/auth"
+	"github.com/flockiot/flock-api/certs"
+	"github.com/flockiot/flock-api/config"
+	"github.com/flockiot/flock-api/events"
+	"github.com/flockiot/flock-api/health"
+	"github.com/flockiot/flock-api/repository"
+)
+
+const pruneInterval = time.Hour
+
+type eventLog interface {
+	ListSince(ctx context.Context, filter repository.EventFilter, afterID int64, limit int) ([]*repository.Event, error)
+}
+
+type Server struct {
+	cfg    config.EventsConfig
+	auth   *auth.Authenticator
+	broker *events.Broker
+	log    eventLog
+
+	origins atomic.Pointer[[]string]
+}
+
+func NewServer(cfg config.EventsConfig, authenticator *auth.Authenticator, broker *events.Broker, log eventLog) *Server {
+	s := &Server{
+		cfg:    cfg,
+		auth:   authenticator,
+		broker: broker,
+		log:    log,
+	}
+	s.SetAllowedOrigins(cfg.AllowedOrigins)
+	return s
+}
+
+// Open connections are not affected.
+func (s *Server) SetAllowedOrigins(origins []string) {
+	s.origins.Store(&origins)
+}
+
+// Start also stops the server if the event listener fails, since its clients
+// would get no events.
+func Start(ctx context.Context, cfg *config.Config, pool *pgxpool.Pool, checks *health.Registry) error {
+	tlsFiles, err := certs.New(cfg.Server)
+	if err != nil {
+		return err
+	}
+	addr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Events.Port)
+	var httpListener health.Listener
+	checks.Register("events-gateway/http", httpListener.Check)
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	httpListener.Serving()
+	defer httpListener.Stopped()
+
+	broker := events.NewBroker(cfg.Events.BufferSize)
+	g, ctx := errgroup.WithContext(ctx)
+
+	var s *Server
+	if pool != nil {
+		eventRepo := repository.NewEventRepository(pool)
+		authenticator := auth.NewAuthenticator(cfg.Auth.AdminToken, repository.NewAPIKeyRepository(pool))
+		s = NewServer(cfg.Events, authenticator, broker, eventRepo)
+
+		listener := events.NewListener(pool, eventRepo, broker)
+		checks.Register("events-gateway/listener", listener.Check)
+		g.Go(func() error {
+			if err := listener.Run(ctx); err != nil {
+				return fmt.Errorf("event listener: %w", err)
+			}
+			return nil
+		})
+		go events.Prune(ctx, eventRepo, cfg.Events.Retention, pruneInterval)
+	} else {
+		s = NewServer(cfg.Events, auth.NewAuthenticator(cfg.Auth.AdminToken, nil), broker, nil)
+	}
+	config.Subscribe(ctx, "events.allowed_origins", func(cfg *config.Config) {
+		s.SetAllowedOrigins(cfg.Events.AllowedOrigins)
+	})
+
+	srv := &http.Server{
+		Addr:    addr,
+		Handler: s.Router(),
+		BaseContext: func(_ net.Listener) context.Context {
+			return ctx
+		},
+	}
+
+	slog.Info("events gateway listening", "addr", addr, "tls", tlsFiles != nil)
+	g.Go(func() error {
+		if tlsFiles != nil {
+			srv.TLSConfig = tlsFiles.TLSConfig("h2", "http/1.1")
+			return srv.ServeTLS(lis, "", "")
+		}
+		return srv.Serve(lis)
+	})
+	g.Go(func() error {
+		<-ctx.Done()
+		slog.Info("events gateway shutting down")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Supervisor.ServerShutdownTimeout())
+		defer cancel()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			slog.Error("events gateway shutdown timed out, closing connections", "error", err)
+			return srv.Close()
+		}
+		return nil
+	})
+	if err := g.Wait(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func (s *Server) Router() chi.Router {
+	r := chi.NewRouter()
+
+	r.Use(middleware.Recoverer)
+	r.Use(middleware.RealIP)
+	r.Use(auth.ClientCertificate)
+
+	r.Get("/livez", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("ok"))
+	})
+
+	r.Group(func(r chi.Router) {
+		r.Use(s.auth.Middleware)
+		r.Get("/v1/organizations/{orgID}/events", s.handleStream)
+		r.Get("/v1/organizations/{orgID}/fleets/{fleetID}/events", s.handleStream)
+		r.Get("/v1/organizations/{orgID}/devices/{deviceID}/events", s.handleStream)
+	})
+
+	return r
+}
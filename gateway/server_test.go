@@ -0,0 +1,295 @@
+package gateway
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+
+	"github.com/flockiot/flock-api/auth"
+	"github.com/flockiot/flock-api/config"
+	"github.com/flockiot/flock-api/events"
+	"github.com/flockiot/flock-api/health"
+	"github.com/flockiot/flock-api/repository"
+)
+
+const testOrg = "11111111-1111-1111-1111-111111111111"
+
+type fakeLog []*repository.Event
+
+func (f fakeLog) ListSince(_ context.Context, filter repository.EventFilter, afterID int64, limit int) ([]*repository.Event, error) {
+	var out []*repository.Event
+	for _, e := range f {
+		if e.ID > afterID && filter.Match(e) && len(out) < limit {
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+type fakeKeys map[string]*repository.APIKey
+
+func (f fakeKeys) GetByToken(_ context.Context, token string) (*repository.APIKey, error) {
+	return f[token], nil
+}
+
+func testServer(t *testing.T) (*httptest.Server, *events.Broker) {
+	t.Helper()
+	broker := events.NewBroker(8)
+	log := fakeLog{
+		{ID: 1, Type: "organization.created", OrganizationID: testOrg, Data: json.RawMessage(`{}`)},
+		{ID: 2, Type: "organization.updated", OrganizationID: testOrg, Data: json.RawMessage(`{}`)},
+		{ID: 3, Type: "organization.created", OrganizationID: "other", Data: json.RawMessage(`{}`)},
+	}
+	authenticator := auth.NewAuthenticator("admin", fakeKeys{
+		"other-key": {ID: "k", OrganizationID: "other"},
+	})
+	s := NewServer(config.EventsConfig{HeartbeatInterval: time.Hour}, authenticator, broker, log)
+	srv := httptest.NewServer(s.Router())
+	t.Cleanup(srv.Close)
+	return srv, broker
+}
+
+func waitForSubscriber(t *testing.T, b *events.Broker) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for b.Len() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for subscriber")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestStreamRequiresAuth(t *testing.T) {
+	srv, _ := testServer(t)
+	resp, err := http.Get(srv.URL + "/v1/organizations/" + testOrg + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", resp.StatusCode)
+	}
+}
+
+func TestStreamRejectsOtherOrganization(t *testing.T) {
+	srv, _ := testServer(t)
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/v1/organizations/"+testOrg+"/events", nil)
+	req.Header.Set("Authorization", "Bearer other-key")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", resp.StatusCode)
+	}
+}
+
+func TestStreamRejectsInvalidLastEventID(t *testing.T) {
+	srv, _ := testServer(t)
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/v1/organizations/"+testOrg+"/events", nil)
+	req.Header.Set("Authorization", "Bearer admin")
+	req.Header.Set("Last-Event-ID", "abc")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", resp.StatusCode)
+	}
+}
+
+func TestSSEResumeAndLive(t *testing.T) {
+	srv, broker := testServer(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/v1/organizations/"+testOrg+"/events", nil)
+	req.Header.Set("Authorization", "Bearer admin")
+	req.Header.Set("Last-Event-ID", "1")
+	broker.Publish(&repository.Event{ID: 3, OrganizationID: "other"}, 3)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("expected text/event-stream, got %q", ct)
+	}
+
+	reader := bufio.NewReader(resp.Body)
+	if id := readSSEID(t, reader); id != "2" {
+		t.Fatalf("expected replayed event 2, got %q", id)
+	}
+
+	waitForSubscriber(t, broker)
+	broker.Publish(&repository.Event{ID: 2, Type: "organization.updated", OrganizationID: testOrg}, 3)
+	broker.Publish(&repository.Event{ID: 4, Type: "organization.updated", OrganizationID: testOrg}, 4)
+	if id := readSSEID(t, reader); id != "4" {
+		t.Fatalf("expected live event 4 after skipping duplicate, got %q", id)
+	}
+}
+
+func readSSEID(t *testing.T, r *bufio.Reader) string {
+	t.Helper()
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("reading stream: %v", err)
+		}
+		if id, ok := strings.CutPrefix(strings.TrimSpace(line), "id: "); ok {
+			return id
+		}
+	}
+}
+
+func TestWebSocketStream(t *testing.T) {
+	srv, broker := testServer(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/v1/organizations/" + testOrg + "/events?types=organization.updated"
+	conn, _, err := websocket.Dial(ctx, url, &websocket.DialOptions{
+		HTTPHeader: http.Header{"Authorization": {"Bearer admin"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.CloseNow()
+
+	waitForSubscriber(t, broker)
+	broker.Publish(&repository.Event{ID: 5, Type: "organization.created", OrganizationID: testOrg}, 5)
+	broker.Publish(&repository.Event{ID: 6, Type: "organization.updated", OrganizationID: testOrg}, 6)
+
+	_, data, err := conn.Read(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var msg eventMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.ID != 6 || msg.Type != "organization.updated" {
+		t.Fatalf("expected event 6 organization.updated, got %+v", msg)
+	}
+}
+
+func TestStreamDeliversEventsCommittedOutOfOrder(t *testing.T) {
+	srv, broker := testServer(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/v1/organizations/" + testOrg + "/events?access_token=admin"
+	conn, _, err := websocket.Dial(ctx, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.CloseNow()
+
+	waitForSubscriber(t, broker)
+	broker.Publish(&repository.Event{ID: 3, OrganizationID: testOrg}, 2)
+	broker.Publish(&repository.Event{ID: 2, OrganizationID: testOrg}, 3)
+
+	for _, want := range []eventMessage{{ID: 3, Cursor: 2}, {ID: 2, Cursor: 3}} {
+		_, data, err := conn.Read(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var msg eventMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			t.Fatal(err)
+		}
+		if msg.ID != want.ID || msg.Cursor != want.Cursor {
+			t.Fatalf("got event %d with cursor %d, want event %d with cursor %d", msg.ID, msg.Cursor, want.ID, want.Cursor)
+		}
+	}
+}
+
+func TestSSEResumeStaysBelowPendingEvents(t *testing.T) {
+	srv, broker := testServer(t)
+	// Event 2 has not been published yet, so 1 is as far as is safe.
+	broker.Publish(&repository.Event{ID: 3, OrganizationID: "other"}, 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/v1/organizations/"+testOrg+"/events", nil)
+	req.Header.Set("Authorization", "Bearer admin")
+	req.Header.Set("Last-Event-ID", "1")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if id := readSSEID(t, bufio.NewReader(resp.Body)); id != "1" {
+		t.Fatalf("expected event 2 to be sent with cursor 1, got %q", id)
+	}
+}
+
+func TestWebSocketSlowConsumerDropped(t *testing.T) {
+	srv, broker := testServer(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/v1/organizations/" + testOrg + "/events?access_token=admin"
+	conn, _, err := websocket.Dial(ctx, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.CloseNow()
+
+	waitForSubscriber(t, broker)
+	for i := range 100 {
+		broker.Publish(&repository.Event{ID: int64(10 + i), OrganizationID: testOrg}, int64(10+i))
+	}
+
+	for {
+		if _, _, err := conn.Read(ctx); err != nil {
+			if websocket.CloseStatus(err) != websocket.StatusTryAgainLater {
+				t.Fatalf("expected try-again-later close, got %v", err)
+			}
+			return
+		}
+	}
+}
+
+func TestStartRegistersListenerCheck(t *testing.T) {
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Server.Host, cfg.Events.Port = "127.0.0.1", 0
+	checks := health.NewRegistry()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- Start(ctx, cfg, nil, checks) }()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, ok := checks.RunPrefix(context.Background(), "events-gateway/"); ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the listener check to pass")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Start() = %v, want nil after cancellation", err)
+	}
+	if _, ok := checks.RunPrefix(context.Background(), "events-gateway/"); ok {
+		t.Fatal("expected the listener check to fail after shutdown")
+	}
+}
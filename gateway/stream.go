@@ -0,0 +1,277 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/go-chi/chi/v5"
+
+	"github.com/flockiot/flock-api/auth"
+	"github.com/flockiot/flock-api/repository"
+)
+
+const (
+	replayBatch  = 500
+	writeTimeout = 10 * time.Second
+)
+
+var errSlowConsumer = errors.New("slow consumer")
+
+type eventMessage struct {
+	ID             int64           `json:"id"`
+	Type           string          `json:"type"`
+	OrganizationID string          `json:"organization_id"`
+	FleetID        string          `json:"fleet_id,omitempty"`
+	DeviceID       string          `json:"device_id,omitempty"`
+	Data           json.RawMessage `json:"data"`
+	CreatedAt      time.Time       `json:"created_at"`
+	Cursor         int64           `json:"cursor"`
+}
+
+func newEventMessage(e *repository.Event, cursor int64) eventMessage {
+	return eventMessage{
+		Cursor:         cursor,
+		ID:             e.ID,
+		Type:           e.Type,
+		OrganizationID: e.OrganizationID,
+		FleetID:        e.FleetID,
+		DeviceID:       e.DeviceID,
+		Data:           e.Data,
+		CreatedAt:      e.CreatedAt,
+	}
+}
+
+type sink interface {
+	send(ctx context.Context, e *repository.Event, cursor int64) error
+	heartbeat(ctx context.Context) error
+}
+
+func (s *Server) handleStream(w http.ResponseWriter, r *http.Request) {
+	filter := repository.EventFilter{
+		OrganizationID: chi.URLParam(r, "orgID"),
+		FleetID:        chi.URLParam(r, "fleetID"),
+		DeviceID:       chi.URLParam(r, "deviceID"),
+		Types:          parseTypes(r.URL.Query().Get("types")),
+	}
+
+	p, _ := auth.FromContext(r.Context())
+	if p == nil || !p.CanAccess(filter.OrganizationID) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	lastID, err := lastEventID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if isWebSocket(r) {
+		s.serveWebSocket(w, r, filter, lastID)
+		return
+	}
+	s.serveSSE(w, r, filter, lastID)
+}
+
+func (s *Server) serveSSE(w http.ResponseWriter, r *http.Request, filter repository.EventFilter, lastID int64) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	out := &sseSink{w: w, rc: http.NewResponseController(w)}
+	if err := out.rc.Flush(); err != nil {
+		slog.ErrorContext(r.Context(), "event stream does not support flushing", "error", err)
+		return
+	}
+
+	s.run(r.Context(), "sse", out, filter, lastID)
+}
+
+func (s *Server) serveWebSocket(w http.ResponseWriter, r *http.Request, filter repository.EventFilter, lastID int64) {
+	conn, err := websocket.Accept(w, r, &websocket.AcceptOptions{
+		OriginPatterns: *s.origins.Load(),
+	})
+	if err != nil {
+		slog.WarnContext(r.Context(), "websocket accept failed", "error", err)
+		return
+	}
+	defer conn.CloseNow()
+
+	ctx := conn.CloseRead(r.Context())
+	err = s.run(ctx, "websocket", &wsSink{conn: conn}, filter, lastID)
+	switch {
+	case errors.Is(err, errSlowConsumer):
+		_ = conn.Close(websocket.StatusTryAgainLater, "slow consumer")
+	case err != nil:
+		_ = conn.Close(websocket.StatusInternalError, "stream error")
+	default:
+		_ = conn.Close(websocket.StatusNormalClosure, "")
+	}
+}
+
+// run subscribes before replaying so nothing published in between is missed.
+// Each event is sent with the cursor to resume from, which stays below any
+// event that may still commit.
+func (s *Server) run(ctx context.Context, transport string, out sink, filter repository.EventFilter, cursor int64) error {
+	sub := s.broker.Subscribe(filter)
+	defer sub.Close()
+
+	start := time.Now()
+	sent := 0
+	logger := slog.With("transport", transport, "organization_id", filter.OrganizationID,
+		"fleet_id", filter.FleetID, "device_id", filter.DeviceID)
+	logger.Info("event stream opened", "last_event_id", cursor)
+
+	err := func() error {
+		var replayed []int64
+		if cursor > 0 && s.log != nil {
+			safe := max(s.broker.Cursor(), cursor)
+			for after := cursor; ; {
+				batch, err := s.log.ListSince(ctx, filter, after, replayBatch)
+				if err != nil {
+					return err
+				}
+				for _, e := range batch {
+					if err := out.send(ctx, e, min(e.ID, safe)); err != nil {
+						return err
+					}
+					replayed = append(replayed, e.ID)
+					cursor = min(e.ID, safe)
+					sent++
+				}
+				if len(batch) < replayBatch {
+					break
+				}
+				after = batch[len(batch)-1].ID
+			}
+		}
+
+		ticker := time.NewTicker(s.cfg.HeartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-sub.Dropped():
+				return errSlowConsumer
+			case m := <-sub.Events():
+				// Replayed events published before m have arrived by now.
+				_, dup := slices.BinarySearch(replayed, m.ID)
+				i, _ := slices.BinarySearch(replayed, m.Cursor+1)
+				if replayed = replayed[i:]; len(replayed) == 0 {
+					replayed = nil
+				}
+				if dup {
+					continue
+				}
+				if err := out.send(ctx, m.Event, m.Cursor); err != nil {
+					return err
+				}
+				cursor = m.Cursor
+				sent++
+			case <-ticker.C:
+				if err := out.heartbeat(ctx); err != nil {
+					return err
+				}
+			}
+		}
+	}()
+
+	if ctx.Err() != nil {
+		err = nil
+	}
+	logger.Info("event stream closed",
+		"events_sent", sent,
+		"last_event_id", cursor,
+		"duration_ms", time.Since(start).Milliseconds(),
+		"error", err,
+	)
+	return err
+}
+
+type sseSink struct {
+	w  http.ResponseWriter
+	rc *http.ResponseController
+}
+
+func (s *sseSink) send(_ context.Context, e *repository.Event, cursor int64) error {
+	data, err := json.Marshal(newEventMessage(e, cursor))
+	if err != nil {
+		return err
+	}
+	_ = s.rc.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if _, err := fmt.Fprintf(s.w, "id: %d\nevent: %s\ndata: %s\n\n", cursor, e.Type, data); err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
+
+func (s *sseSink) heartbeat(_ context.Context) error {
+	_ = s.rc.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if _, err := fmt.Fprint(s.w, ": heartbeat\n\n"); err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
+
+type wsSink struct {
+	conn *websocket.Conn
+}
+
+func (s *wsSink) send(ctx context.Context, e *repository.Event, cursor int64) error {
+	data, err := json.Marshal(newEventMessage(e, cursor))
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, writeTimeout)
+	defer cancel()
+	return s.conn.Write(ctx, websocket.MessageText, data)
+}
+
+func (s *wsSink) heartbeat(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, writeTimeout)
+	defer cancel()
+	return s.conn.Ping(ctx)
+}
+
+func isWebSocket(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket")
+}
+
+// lastEventID falls back to the last_event_id query parameter for WebSocket
+// and first-time EventSource clients.
+func lastEventID(r *http.Request) (int64, error) {
+	raw := r.Header.Get("Last-Event-ID")
+	if raw == "" {
+		raw = r.URL.Query().Get("last_event_id")
+	}
+	if raw == "" {
+		return 0, nil
+	}
+	id, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || id < 0 {
+		return 0, fmt.Errorf("invalid last event id %q", raw)
+	}
+	return id, nil
+}
+
+func parseTypes(raw string) []string {
+	var types []string
+	for _, t := range strings.Split(raw, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			types = append(types, t)
+		}
+	}
+	return types
+}
@@ -0,0 +1,149 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const checkTimeout = 2 * time.Second
+
+var (
+	ErrDatabaseNotConfigured = errors.New("database not configured")
+	ErrDatabaseUnreachable   = errors.New("database unreachable")
+	ErrShuttingDown          = errors.New("shutting down")
+	ErrNotListening          = errors.New("not listening")
+)
+
+type Check func(ctx context.Context) error
+
+type Registry struct {
+	mu       sync.RWMutex
+	checks   map[string]Check
+	order    []string
+	draining atomic.Bool
+}
+
+func NewRegistry() *Registry {
+	return &Registry{checks: make(map[string]Check)}
+}
+
+// Register replaces a check with the same name, so a restarted target can
+// register again.
+func (r *Registry) Register(name string, check Check) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.checks[name]; !ok {
+		r.order = append(r.order, name)
+	}
+	r.checks[name] = check
+}
+
+type Result struct {
+	Name     string        `json:"name"`
+	Error    error         `json:"-"`
+	Duration time.Duration `json:"-"`
+}
+
+func (r Result) OK() bool {
+	return r.Error == nil
+}
+
+// Drain lets load balancers stop routing to the process before its listeners
+// close.
+func (r *Registry) Drain() {
+	r.draining.Store(true)
+}
+
+func (r *Registry) Run(ctx context.Context, exclude ...string) (results []Result, ok bool) {
+	results, ok = r.run(ctx, func(name string) bool { return !slices.Contains(exclude, name) })
+	if r.draining.Load() {
+		results = append(results, Result{Name: "shutdown", Error: ErrShuttingDown})
+		ok = false
+	}
+	return results, ok
+}
+
+func (r *Registry) RunPrefix(ctx context.Context, prefix string) (results []Result, ok bool) {
+	return r.run(ctx, func(name string) bool { return strings.HasPrefix(name, prefix) })
+}
+
+func (r *Registry) run(ctx context.Context, keep func(name string) bool) (results []Result, ok bool) {
+	r.mu.RLock()
+	names := make([]string, 0, len(r.order))
+	checks := make([]Check, 0, len(r.order))
+	for _, name := range r.order {
+		if keep(name) {
+			names = append(names, name)
+			checks = append(checks, r.checks[name])
+		}
+	}
+	r.mu.RUnlock()
+
+	results = make([]Result, len(names))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+			defer cancel()
+			start := time.Now()
+			results[i] = Result{Name: names[i], Error: runCheck(ctx, check), Duration: time.Since(start)}
+		}()
+	}
+	wg.Wait()
+
+	ok = true
+	for _, res := range results {
+		ok = ok && res.OK()
+	}
+	return results, ok
+}
+
+func runCheck(ctx context.Context, check Check) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = fmt.Errorf("check panicked: %v", v)
+		}
+	}()
+	return check(ctx)
+}
+
+func Database(pool *pgxpool.Pool) Check {
+	return func(ctx context.Context) error {
+		if pool == nil {
+			return ErrDatabaseNotConfigured
+		}
+		if err := pool.Ping(ctx); err != nil {
+			return fmt.Errorf("%w: %w", ErrDatabaseUnreachable, err)
+		}
+		return nil
+	}
+}
+
+type Listener struct {
+	serving atomic.Bool
+}
+
+func (l *Listener) Serving() {
+	l.serving.Store(true)
+}
+
+func (l *Listener) Stopped() {
+	l.serving.Store(false)
+}
+
+func (l *Listener) Check(_ context.Context) error {
+	if !l.serving.Load() {
+		return ErrNotListening
+	}
+	return nil
+}
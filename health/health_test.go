@@ -0,0 +1,105 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestRunReportsEveryCheckInOrder(t *testing.T) {
+	r := NewRegistry()
+	r.Register("database", func(context.Context) error { return nil })
+	r.Register("ingester/buffer", func(context.Context) error { return errors.New("buffer full") })
+
+	results, ok := r.Run(context.Background())
+	if ok {
+		t.Fatal("expected failure when a check fails")
+	}
+	if len(results) != 2 || results[0].Name != "database" || results[1].Name != "ingester/buffer" {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+	if !results[0].OK() || results[1].Error == nil || results[1].Error.Error() != "buffer full" {
+		t.Fatalf("unexpected outcomes: %+v", results)
+	}
+}
+
+func TestRunExclude(t *testing.T) {
+	r := NewRegistry()
+	r.Register("database", func(context.Context) error { return errors.New("down") })
+	r.Register("tunnel", func(context.Context) error { return nil })
+
+	results, ok := r.Run(context.Background(), "database")
+	if !ok || len(results) != 1 || results[0].Name != "tunnel" {
+		t.Fatalf("expected only tunnel to run, got ok=%v %+v", ok, results)
+	}
+}
+
+func TestDrainFailsRun(t *testing.T) {
+	r := NewRegistry()
+	r.Register("database", func(context.Context) error { return nil })
+	r.Drain()
+
+	results, ok := r.Run(context.Background())
+	if ok {
+		t.Fatal("expected Run to fail while draining")
+	}
+	last := results[len(results)-1]
+	if last.Name != "shutdown" || !errors.Is(last.Error, ErrShuttingDown) {
+		t.Fatalf("expected a shutdown result, got %+v", last)
+	}
+	if _, ok := r.RunPrefix(context.Background(), "database"); !ok {
+		t.Fatal("draining should not affect dependency readiness")
+	}
+}
+
+func TestRunPrefix(t *testing.T) {
+	r := NewRegistry()
+	r.Register("tunnel/listener", func(context.Context) error { return nil })
+	r.Register("proxy/upstream", func(context.Context) error { return errors.New("down") })
+
+	results, ok := r.RunPrefix(context.Background(), "tunnel/")
+	if !ok || len(results) != 1 || results[0].Name != "tunnel/listener" {
+		t.Fatalf("expected only tunnel/listener, got ok=%v %+v", ok, results)
+	}
+}
+
+func TestRegisterReplaces(t *testing.T) {
+	r := NewRegistry()
+	r.Register("webhooks/dispatcher", func(context.Context) error { return errors.New("old") })
+	r.Register("webhooks/dispatcher", func(context.Context) error { return nil })
+
+	results, ok := r.Run(context.Background())
+	if !ok || len(results) != 1 {
+		t.Fatalf("expected the replacement check only, got ok=%v %+v", ok, results)
+	}
+}
+
+func TestRunRecoversPanics(t *testing.T) {
+	r := NewRegistry()
+	r.Register("broken", func(context.Context) error { panic("oops") })
+
+	if _, ok := r.Run(context.Background()); ok {
+		t.Fatal("expected a panicking check to fail")
+	}
+}
+
+func TestDatabaseWithoutPool(t *testing.T) {
+	if err := Database(nil)(context.Background()); !errors.Is(err, ErrDatabaseNotConfigured) {
+		t.Fatalf("expected ErrDatabaseNotConfigured, got %v", err)
+	}
+}
+
+func TestListener(t *testing.T) {
+	var l Listener
+	if err := l.Check(context.Background()); !errors.Is(err, ErrNotListening) {
+		t.Fatalf("before Serving: got %v, want ErrNotListening", err)
+	}
+	l.Serving()
+	if err := l.Check(context.Background()); err != nil {
+		t.Fatalf("while serving: got %v", err)
+	}
+	l.Stopped()
+	if err := l.Check(context.Background()); !errors.Is(err, ErrNotListening) {
+		t.Fatalf("after Stopped: got %v, want ErrNotListening", err)
+	}
+}
@@ -0,0 +1,169 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"slices"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+type contextKey struct{}
+
+// fields are copied on write so a child context never changes what its
+// parent logs.
+type fields struct {
+	requestID string
+	attrs     []slog.Attr
+}
+
+func fromContext(ctx context.Context) fields {
+	f, _ := ctx.Value(contextKey{}).(fields)
+	return f
+}
+
+func WithRequestID(ctx context.Context, id string) context.Context {
+	f := fromContext(ctx)
+	f.requestID = id
+	return context.WithValue(ctx, contextKey{}, f)
+}
+
+func RequestID(ctx context.Context) string {
+	return fromContext(ctx).requestID
+}
+
+func With(ctx context.Context, args ...any) context.Context {
+	f := fromContext(ctx)
+	attrs := make([]slog.Attr, len(f.attrs), len(f.attrs)+len(args)/2)
+	copy(attrs, f.attrs)
+	r := slog.NewRecord(time.Time{}, 0, "", 0)
+	r.Add(args...)
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+	f.attrs = attrs
+	return context.WithValue(ctx, contextKey{}, f)
+}
+
+// FromContext binds ctx so records logged without one still carry the
+// request ID, attributes and trace.
+func FromContext(ctx context.Context) *slog.Logger {
+	return slog.New(boundHandler{Handler: slog.Default().Handler(), ctx: ctx})
+}
+
+const requestIDMaxLen = 128
+
+// RequestIDOrNew replaces an id that is unsafe to log or echo back.
+func RequestIDOrNew(id string) string {
+	if id != "" && len(id) <= requestIDMaxLen && isPrintableASCII(id) {
+		return id
+	}
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func isPrintableASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < 0x21 || s[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+func ContextHandler(h slog.Handler) slog.Handler {
+	return contextHandler{Handler: h}
+}
+
+type contextHandler struct {
+	slog.Handler
+	// Context attributes belong at the top level, so once a group is open
+	// they are added to root, the handler before it, and the calls since
+	// replayed on top.
+	root  slog.Handler
+	after []handlerStep
+}
+
+type handlerStep struct {
+	group string
+	attrs []slog.Attr
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	f := fromContext(ctx)
+	var attrs []slog.Attr
+	if f.requestID != "" {
+		attrs = append(attrs, slog.String("request_id", f.requestID))
+	}
+	attrs = append(attrs, f.attrs...)
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		attrs = append(attrs,
+			slog.String("trace_id", sc.TraceID().String()),
+			slog.String("span_id", sc.SpanID().String()),
+		)
+	}
+	if h.root == nil || len(attrs) == 0 {
+		r.AddAttrs(attrs...)
+		return h.Handler.Handle(ctx, r)
+	}
+
+	handler := h.root.WithAttrs(attrs)
+	for _, step := range h.after {
+		if step.group != "" {
+			handler = handler.WithGroup(step.group)
+		} else {
+			handler = handler.WithAttrs(step.attrs)
+		}
+	}
+	return handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	return h.with(handlerStep{attrs: attrs}, h.Handler.WithAttrs(attrs))
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	if h.root == nil {
+		h.root = h.Handler
+	}
+	return h.with(handlerStep{group: name}, h.Handler.WithGroup(name))
+}
+
+func (h contextHandler) with(step handlerStep, next slog.Handler) contextHandler {
+	if h.root == nil {
+		return contextHandler{Handler: next}
+	}
+	return contextHandler{Handler: next, root: h.root, after: append(slices.Clip(h.after), step)}
+}
+
+// slog.Logger's Info, Error and friends log without a context.
+type boundHandler struct {
+	slog.Handler
+	ctx context.Context
+}
+
+func (h boundHandler) Handle(ctx context.Context, r slog.Record) error {
+	if ctx == nil || ctx == context.Background() {
+		ctx = h.ctx
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h boundHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return boundHandler{Handler: h.Handler.WithAttrs(attrs), ctx: h.ctx}
+}
+
+func (h boundHandler) WithGroup(name string) slog.Handler {
+	return boundHandler{Handler: h.Handler.WithGroup(name), ctx: h.ctx}
+}
@@ -0,0 +1,105 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func decodeLine(t *testing.T, buf *bytes.Buffer) map[string]any {
+	t.Helper()
+	var m map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &m); err != nil {
+		t.Fatalf("failed to decode log line %q: %v", buf.String(), err)
+	}
+	buf.Reset()
+	return m
+}
+
+func TestContextHandlerAddsFields(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(ContextHandler(slog.NewJSONHandler(&buf, nil)))
+
+	ctx := WithRequestID(context.Background(), "req-1")
+	ctx = With(ctx, "organization_id", "org-1")
+	logger.InfoContext(ctx, "hello")
+
+	m := decodeLine(t, &buf)
+	if m["request_id"] != "req-1" || m["organization_id"] != "org-1" {
+		t.Fatalf("expected request and org fields, got %v", m)
+	}
+}
+
+func TestContextHandlerKeepsFieldsOutOfGroups(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(ContextHandler(slog.NewJSONHandler(&buf, nil))).With("component", "api")
+
+	ctx := WithRequestID(context.Background(), "req-1")
+	logger.WithGroup("g").With("a", 1).WithGroup("h").InfoContext(ctx, "hello", "b", 2)
+
+	m := decodeLine(t, &buf)
+	if m["request_id"] != "req-1" || m["component"] != "api" {
+		t.Fatalf("expected top-level request ID and component, got %v", m)
+	}
+	g, _ := m["g"].(map[string]any)
+	h, _ := g["h"].(map[string]any)
+	if g["a"] != float64(1) || h["b"] != float64(2) || g["request_id"] != nil || h["request_id"] != nil {
+		t.Fatalf("expected only the logged attributes in the groups, got %v", m)
+	}
+
+	logger.WithGroup("g").Info("no context", "b", 2)
+	if m := decodeLine(t, &buf); m["request_id"] != nil || m["g"].(map[string]any)["b"] != float64(2) {
+		t.Fatalf("unexpected record without context: %v", m)
+	}
+}
+
+func TestWithDoesNotModifyParent(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(ContextHandler(slog.NewJSONHandler(&buf, nil)))
+
+	parent := With(context.Background(), "a", 1)
+	child := With(parent, "b", 2)
+	_ = With(parent, "c", 3)
+
+	logger.InfoContext(parent, "parent")
+	if m := decodeLine(t, &buf); m["b"] != nil || m["c"] != nil {
+		t.Fatalf("parent picked up child attributes: %v", m)
+	}
+	logger.InfoContext(child, "child")
+	if m := decodeLine(t, &buf); m["a"] == nil || m["b"] == nil || m["c"] != nil {
+		t.Fatalf("unexpected child attributes: %v", m)
+	}
+}
+
+func TestFromContextBindsContext(t *testing.T) {
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(ContextHandler(slog.NewJSONHandler(&buf, nil))))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+
+	ctx := WithRequestID(context.Background(), "req-2")
+	FromContext(ctx).With("component", "test").Info("no context passed")
+
+	m := decodeLine(t, &buf)
+	if m["request_id"] != "req-2" || m["component"] != "test" {
+		t.Fatalf("expected bound request ID, got %v", m)
+	}
+}
+
+func TestRequestIDOrNew(t *testing.T) {
+	if got := RequestIDOrNew("abc-123"); got != "abc-123" {
+		t.Fatalf("expected caller's ID to be kept, got %q", got)
+	}
+	for _, bad := range []string{"", "has space", "new\nline", strings.Repeat("x", 129)} {
+		got := RequestIDOrNew(bad)
+		if got == bad || len(got) != 32 {
+			t.Errorf("RequestIDOrNew(%q) = %q, want a generated ID", bad, got)
+		}
+	}
+	if RequestIDOrNew("") == RequestIDOrNew("") {
+		t.Fatal("generated IDs should differ")
+	}
+}
@@ -0,0 +1,76 @@
+package logging
+
+import (
+	"log/slog"
+	"strings"
+)
+
+const ecsVersion = "8.11.0"
+
+func logfmtAttr(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) > 0 {
+		return a
+	}
+	switch a.Key {
+	case slog.TimeKey:
+		a.Key = "ts"
+	case slog.LevelKey:
+		a.Value = slog.StringValue(strings.ToLower(a.Value.String()))
+	}
+	return a
+}
+
+func gcpAttr(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) > 0 {
+		return a
+	}
+	switch a.Key {
+	case slog.TimeKey:
+		a.Key = "timestamp"
+	case slog.MessageKey:
+		a.Key = "message"
+	case slog.LevelKey:
+		a.Key = "severity"
+		a.Value = slog.StringValue(gcpSeverity(a.Value.Any().(slog.Level)))
+	case "span_id":
+		a.Key = "logging.googleapis.com/spanId"
+	}
+	return a
+}
+
+func gcpSeverity(l slog.Level) string {
+	switch {
+	case l >= slog.LevelError:
+		return "ERROR"
+	case l >= slog.LevelWarn:
+		return "WARNING"
+	case l >= slog.LevelInfo:
+		return "INFO"
+	default:
+		return "DEBUG"
+	}
+}
+
+func ecsAttr(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) > 0 {
+		return a
+	}
+	switch a.Key {
+	case slog.TimeKey:
+		a.Key = "@timestamp"
+	case slog.MessageKey:
+		a.Key = "message"
+	case slog.LevelKey:
+		a.Key = "log.level"
+		a.Value = slog.StringValue(strings.ToLower(a.Value.String()))
+	case "trace_id":
+		a.Key = "trace.id"
+	case "span_id":
+		a.Key = "span.id"
+	case "request_id":
+		a.Key = "http.request.id"
+	case "error":
+		a.Key = "error.message"
+	}
+	return a
+}
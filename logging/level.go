@@ -0,0 +1,110 @@
+package logging
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"math"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+const modulePath = "github.com/flockiot/flock-api/"
+
+type componentLevels struct {
+	levels map[string]slog.Level
+	min    slog.Level
+}
+
+var (
+	components   atomic.Pointer[componentLevels]
+	pcComponents sync.Map
+)
+
+func Level() slog.Level {
+	return level.Level()
+}
+
+func SetLevel(name string) error {
+	lvl, err := parseLevel(name)
+	if err != nil {
+		return err
+	}
+	level.Set(lvl)
+	return nil
+}
+
+func ComponentLevels() map[string]slog.Level {
+	c := components.Load()
+	if c == nil {
+		return map[string]slog.Level{}
+	}
+	return maps.Clone(c.levels)
+}
+
+// An override also covers the packages below its component.
+func SetComponentLevels(levels map[string]string) error {
+	if len(levels) == 0 {
+		components.Store(nil)
+		return nil
+	}
+	c := &componentLevels{levels: make(map[string]slog.Level, len(levels)), min: slog.Level(math.MaxInt)}
+	for name, levelName := range levels {
+		lvl, err := parseLevel(levelName)
+		if err != nil {
+			return fmt.Errorf("component %s: %w", name, err)
+		}
+		c.levels[strings.Trim(name, "/")] = lvl
+		c.min = min(c.min, lvl)
+	}
+	components.Store(c)
+	return nil
+}
+
+// minLevel lets records below every level be dropped before they are built.
+func minLevel() slog.Level {
+	global := level.Level()
+	if c := components.Load(); c != nil {
+		return min(global, c.min)
+	}
+	return global
+}
+
+// The longest matching component wins.
+func (c *componentLevels) levelFor(pc uintptr) slog.Level {
+	comp := component(pc)
+	for {
+		if lvl, ok := c.levels[comp]; ok {
+			return lvl
+		}
+		i := strings.LastIndexByte(comp, '/')
+		if i < 0 {
+			return level.Level()
+		}
+		comp = comp[:i]
+	}
+}
+
+func component(pc uintptr) string {
+	if pc == 0 {
+		return ""
+	}
+	if c, ok := pcComponents.Load(pc); ok {
+		return c.(string)
+	}
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	comp := componentOf(frame.Function)
+	pcComponents.Store(pc, comp)
+	return comp
+}
+
+func componentOf(fn string) string {
+	dir := ""
+	if i := strings.LastIndexByte(fn, '/'); i >= 0 {
+		dir, fn = fn[:i+1], fn[i+1:]
+	}
+	pkg, _, _ := strings.Cut(fn, ".")
+	return strings.TrimPrefix(dir+pkg, modulePath)
+}
@@ -0,0 +1,56 @@
+package logging
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestComponentLevels(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Setup("info", "text", &buf); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = SetComponentLevels(nil) })
+
+	cases := []struct {
+		levels map[string]string
+		logged bool
+		level  slog.Level
+	}{
+		{nil, false, slog.LevelDebug},
+		{map[string]string{"api": "debug"}, false, slog.LevelDebug},
+		{map[string]string{"logging": "debug"}, true, slog.LevelDebug},
+		{map[string]string{"logging": "error"}, false, slog.LevelInfo},
+	}
+	for _, tc := range cases {
+		if err := SetComponentLevels(tc.levels); err != nil {
+			t.Fatal(err)
+		}
+		buf.Reset()
+		slog.Log(t.Context(), tc.level, "component-record")
+		if got := strings.Contains(buf.String(), "component-record"); got != tc.logged {
+			t.Errorf("levels %v: logged = %v, want %v", tc.levels, got, tc.logged)
+		}
+	}
+}
+
+func TestComponent(t *testing.T) {
+	cases := map[string]string{
+		"github.com/flockiot/flock-api/repository.(*OrganizationRepository).Get": "repository",
+		"github.com/flockiot/flock-api/proto/flock/v1.init":                      "proto/flock/v1",
+		"main.main": "main",
+	}
+	for fn, want := range cases {
+		if got := componentOf(fn); got != want {
+			t.Errorf("componentOf(%q) = %q, want %q", fn, got, want)
+		}
+	}
+}
+
+func TestSetComponentLevelsRejectsUnknownLevel(t *testing.T) {
+	if err := SetComponentLevels(map[string]string{"api": "loud"}); err == nil {
+		t.Fatal("expected error for invalid level")
+	}
+}
@@ -1,31 +1,50 @@
 package logging
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"log/slog"
 	"strings"
+	"sync/atomic"
 )
 
-func Setup(level, format string, w io.Writer) error {
-	lvl, err := parseLevel(level)
+var (
+	level slog.LevelVar
+	// base is swapped by Setup; existing loggers follow.
+	base     atomic.Pointer[slog.Handler]
+	redactor atomic.Pointer[Redactor]
+	sampler  atomic.Pointer[Sampler]
+)
+
+// SetRedactor applies to existing loggers too. Nil turns redaction off.
+func SetRedactor(r *Redactor) {
+	redactor.Store(r)
+}
+
+// Nil logs every record.
+func SetSampler(s *Sampler) {
+	sampler.Store(s)
+}
+
+// Setup keeps component levels when called again at runtime.
+func Setup(levelName, format string, w io.Writer) error {
+	return SetupSinks(levelName, Sink{Writer: w, Format: format})
+}
+
+func SetupSinks(levelName string, sinks ...Sink) error {
+	lvl, err := parseLevel(levelName)
 	if err != nil {
 		return err
 	}
-
-	opts := &slog.HandlerOptions{Level: lvl}
-
-	var handler slog.Handler
-	switch strings.ToLower(format) {
-	case "json":
-		handler = slog.NewJSONHandler(w, opts)
-	case "text":
-		handler = slog.NewTextHandler(w, opts)
-	default:
-		return fmt.Errorf("unknown log format %q (valid: text, json)", format)
+	handler, err := newSinksHandler(sinks)
+	if err != nil {
+		return err
 	}
 
-	slog.SetDefault(slog.New(handler))
+	level.Set(lvl)
+	base.Store(&handler)
+	slog.SetDefault(slog.New(ContextHandler(&swapHandler{})))
 	return nil
 }
 
@@ -43,3 +62,66 @@ func parseLevel(s string) (slog.Level, error) {
 		return 0, fmt.Errorf("unknown log level %q (valid: debug, info, warn, error)", s)
 	}
 }
+
+// swapHandler replays WithAttrs and WithGroup onto a new base or redactor.
+type swapHandler struct {
+	parent *swapHandler
+	attrs  []slog.Attr
+	group  string
+
+	cache atomic.Pointer[resolved]
+}
+
+type resolved struct {
+	base     *slog.Handler
+	redactor *Redactor
+	handler  slog.Handler
+}
+
+func (h *swapHandler) current() slog.Handler {
+	b, red := base.Load(), redactor.Load()
+	if c := h.cache.Load(); c != nil && c.base == b && c.redactor == red {
+		return c.handler
+	}
+	var handler slog.Handler
+	switch {
+	case h.parent == nil && red != nil:
+		handler = NewRedactHandler(*b, red)
+	case h.parent == nil:
+		handler = *b
+	case h.group != "":
+		handler = h.parent.current().WithGroup(h.group)
+	default:
+		handler = h.parent.current().WithAttrs(h.attrs)
+	}
+	h.cache.Store(&resolved{base: b, redactor: red, handler: handler})
+	return handler
+}
+
+func (h *swapHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	return l >= minLevel() && h.current().Enabled(ctx, l)
+}
+
+func (h *swapHandler) Handle(ctx context.Context, r slog.Record) error {
+	if c := components.Load(); c != nil && r.Level < c.levelFor(r.PC) {
+		return nil
+	}
+	if s := sampler.Load(); s != nil && !s.Allow(r) {
+		return nil
+	}
+	return h.current().Handle(ctx, r)
+}
+
+func (h *swapHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	return &swapHandler{parent: h, attrs: attrs}
+}
+
+func (h *swapHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &swapHandler{parent: h, group: name}
+}
@@ -2,9 +2,12 @@ package logging
 
 import (
 	"bytes"
+	"context"
 	"log/slog"
 	"strings"
 	"testing"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
 func TestSetupTextFormat(t *testing.T) {
@@ -76,3 +79,54 @@ func TestParseLevelCaseInsensitive(t *testing.T) {
 		}
 	}
 }
+
+func TestSetupAddsTraceIDs(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Setup("info", "json", &buf); err != nil {
+		t.Fatal(err)
+	}
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		SpanID:     trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+		TraceFlags: trace.FlagsSampled,
+	})
+	ctx := trace.ContextWithSpanContext(context.Background(), sc)
+
+	slog.With("component", "test").InfoContext(ctx, "traced")
+	out := buf.String()
+	if !strings.Contains(out, `"trace_id":"4bf92f3577b34da6a3ce929d0e0e4736"`) ||
+		!strings.Contains(out, `"span_id":"00f067aa0ba902b7"`) {
+		t.Fatalf("expected trace and span IDs, got: %s", out)
+	}
+
+	buf.Reset()
+	slog.Info("untraced")
+	if strings.Contains(buf.String(), "trace_id") {
+		t.Fatalf("expected no trace ID without a span, got: %s", buf.String())
+	}
+}
+
+func TestSetupReconfiguresExistingLoggers(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Setup("info", "text", &buf); err != nil {
+		t.Fatal(err)
+	}
+	logger := slog.With("component", "test").WithGroup("req")
+
+	if err := Setup("debug", "json", &buf); err != nil {
+		t.Fatal(err)
+	}
+	logger.Debug("reconfigured", "id", 7)
+	if out := buf.String(); !strings.Contains(out, `"component":"test","req":{"id":7}`) {
+		t.Fatalf("expected JSON debug record with attributes and group, got: %s", out)
+	}
+
+	buf.Reset()
+	if err := Setup("warn", "bogus", &buf); err == nil {
+		t.Fatal("expected error for invalid format")
+	}
+	logger.Debug("still-debug")
+	if !strings.Contains(buf.String(), "still-debug") {
+		t.Fatal("a failed Setup should leave the level unchanged")
+	}
+}
@@ -0,0 +1,120 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path"
+	"regexp"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+var (
+	bearerToken = regexp.MustCompile(`(?i)\b(bearer\s+)[A-Za-z0-9\-._~+/]+=*`)
+	urlPassword = regexp.MustCompile(`\b([a-zA-Z][a-zA-Z0-9+.\-]*://[^:/@\s]*:)[^@\s]+@`)
+	// dsnPassword matches the key=value form of a Postgres connection string.
+	dsnPassword = regexp.MustCompile(`(?i)\b(password\s*=\s*)('[^']*'|\S+)`)
+)
+
+type Redactor struct {
+	keys   []string
+	values bool
+}
+
+// Keys are case-insensitive globs. With values set, bearer tokens and URL
+// passwords are masked inside any string or error value.
+func NewRedactor(keys []string, values bool) (*Redactor, error) {
+	r := &Redactor{values: values}
+	for _, k := range keys {
+		k = strings.ToLower(strings.TrimSpace(k))
+		if k == "" {
+			continue
+		}
+		if _, err := path.Match(k, ""); err != nil {
+			return nil, fmt.Errorf("redact key pattern %q: %w", k, err)
+		}
+		r.keys = append(r.keys, k)
+	}
+	return r, nil
+}
+
+func (r *Redactor) matchKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, pattern := range r.keys {
+		if ok, _ := path.Match(pattern, key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Redactor) String(s string) string {
+	if !r.values {
+		return s
+	}
+	s = bearerToken.ReplaceAllString(s, "${1}"+redacted)
+	s = dsnPassword.ReplaceAllString(s, "${1}xxxxx")
+	return urlPassword.ReplaceAllString(s, "${1}xxxxx@")
+}
+
+func (r *Redactor) Attr(a slog.Attr) slog.Attr {
+	if a.Key != "" && r.matchKey(a.Key) {
+		return slog.String(a.Key, redacted)
+	}
+	v := a.Value.Resolve()
+	switch v.Kind() {
+	case slog.KindGroup:
+		attrs := v.Group()
+		out := make([]slog.Attr, len(attrs))
+		for i, ga := range attrs {
+			out[i] = r.Attr(ga)
+		}
+		return slog.Attr{Key: a.Key, Value: slog.GroupValue(out...)}
+	case slog.KindString:
+		return slog.String(a.Key, r.String(v.String()))
+	case slog.KindAny:
+		if err, ok := v.Any().(error); ok && r.values {
+			msg := err.Error()
+			if masked := r.String(msg); masked != msg {
+				return slog.String(a.Key, masked)
+			}
+		}
+	}
+	return slog.Attr{Key: a.Key, Value: v}
+}
+
+func NewRedactHandler(h slog.Handler, r *Redactor) slog.Handler {
+	return &redactHandler{next: h, r: r}
+}
+
+type redactHandler struct {
+	next slog.Handler
+	r    *Redactor
+}
+
+func (h *redactHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	return h.next.Enabled(ctx, l)
+}
+
+func (h *redactHandler) Handle(ctx context.Context, rec slog.Record) error {
+	out := slog.NewRecord(rec.Time, rec.Level, h.r.String(rec.Message), rec.PC)
+	rec.Attrs(func(a slog.Attr) bool {
+		out.AddAttrs(h.r.Attr(a))
+		return true
+	})
+	return h.next.Handle(ctx, out)
+}
+
+func (h *redactHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	out := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		out[i] = h.r.Attr(a)
+	}
+	return &redactHandler{next: h.next.WithAttrs(out), r: h.r}
+}
+
+func (h *redactHandler) WithGroup(name string) slog.Handler {
+	return &redactHandler{next: h.next.WithGroup(name), r: h.r}
+}
@@ -0,0 +1,91 @@
+package logging
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func testRedactor(t *testing.T) *Redactor {
+	t.Helper()
+	r, err := NewRedactor([]string{"*token*", "*password*", "dsn", "Authorization"}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestRedactHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewRedactHandler(slog.NewJSONHandler(&buf, nil), testRedactor(t)))
+
+	cases := []struct {
+		name   string
+		log    func(*slog.Logger)
+		want   string
+		secret string
+	}{
+		{"key", func(l *slog.Logger) { l.Info("m", "admin_token", "s3cret") }, `"admin_token":"[REDACTED]"`, "s3cret"},
+		{"key case", func(l *slog.Logger) { l.Info("m", "AUTHORIZATION", "Basic s3cret") }, `"AUTHORIZATION":"[REDACTED]"`, "s3cret"},
+		{"nested group", func(l *slog.Logger) {
+			l.Info("m", slog.Group("db", slog.Group("conn", "password", "s3cret", "host", "db1")))
+		}, `"db":{"conn":{"password":"[REDACTED]","host":"db1"}}`, "s3cret"},
+		{"group key", func(l *slog.Logger) { l.Info("m", slog.Group("dsn", "user", "flock", "pass", "s3cret")) }, `"dsn":"[REDACTED]"`, "s3cret"},
+		{"with attrs in group", func(l *slog.Logger) {
+			l.WithGroup("req").With("refresh_token", "s3cret").Info("m", "path", "/v1")
+		}, `"req":{"refresh_token":"[REDACTED]","path":"/v1"}`, "s3cret"},
+		{"url password", func(l *slog.Logger) { l.Info("m", "target", "postgres://flock:s3cret@db:5432/flock") }, `"target":"postgres://flock:xxxxx@db:5432/flock"`, "s3cret"},
+		{"keyword password", func(l *slog.Logger) { l.Info("m", "conn", "host=db password=s3cret user=flock") }, `"conn":"host=db password=xxxxx user=flock"`, "s3cret"},
+		{"bearer in error", func(l *slog.Logger) {
+			l.Error("m", "error", errors.New("upstream rejected Bearer s3cret.abc"))
+		}, `"error":"upstream rejected Bearer [REDACTED]"`, "s3cret"},
+		{"message", func(l *slog.Logger) { l.Info("dialing postgres://flock:s3cret@db/flock") }, `"msg":"dialing postgres://flock:xxxxx@db/flock"`, "s3cret"},
+		{"untouched", func(l *slog.Logger) { l.Info("m", "key_id", "k1", "count", 3) }, `"key_id":"k1","count":3`, ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			buf.Reset()
+			tc.log(logger)
+			out := buf.String()
+			if !strings.Contains(out, tc.want) {
+				t.Errorf("expected %s in output, got: %s", tc.want, out)
+			}
+			if tc.secret != "" && strings.Contains(out, tc.secret) {
+				t.Errorf("secret leaked: %s", out)
+			}
+		})
+	}
+}
+
+func TestRedactorWithoutValueDetection(t *testing.T) {
+	r, err := NewRedactor(nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := r.String("postgres://flock:pw@db"); got != "postgres://flock:pw@db" {
+		t.Fatalf("String() = %q, want it unchanged", got)
+	}
+}
+
+func TestNewRedactorRejectsBadPattern(t *testing.T) {
+	if _, err := NewRedactor([]string{"[token"}, true); err == nil {
+		t.Fatal("expected error for malformed pattern")
+	}
+}
+
+func TestSetRedactorAppliesToExistingLoggers(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Setup("info", "json", &buf); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { SetRedactor(nil) })
+	logger := slog.With("api_token", "s3cret")
+
+	SetRedactor(testRedactor(t))
+	logger.Info("m")
+	if out := buf.String(); strings.Contains(out, "s3cret") || !strings.Contains(out, `"api_token":"[REDACTED]"`) {
+		t.Fatalf("expected the token to be redacted, got: %s", out)
+	}
+}
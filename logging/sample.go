@@ -0,0 +1,66 @@
+package logging
+
+import (
+	"hash/fnv"
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+var sampledOut atomic.Uint64
+
+func SampledOut() uint64 {
+	return sampledOut.Load()
+}
+
+// Messages are hashed into a fixed table, so two that share a counter are
+// sampled together.
+const sampleCounters = 4096
+
+// Warnings and errors are always kept.
+type Sampler struct {
+	first      uint64
+	thereafter uint64
+	interval   time.Duration
+
+	counters [sampleCounters]sampleCounter
+}
+
+type sampleCounter struct {
+	windowEnd atomic.Int64
+	n         atomic.Uint64
+}
+
+// A thereafter of 0 drops the rest of the interval.
+func NewSampler(first, thereafter int, interval time.Duration) *Sampler {
+	return &Sampler{first: uint64(first), thereafter: uint64(thereafter), interval: interval}
+}
+
+func (s *Sampler) Allow(r slog.Record) bool {
+	if r.Level >= slog.LevelWarn {
+		return true
+	}
+	c := &s.counters[sampleIndex(r.Level, r.Message)]
+
+	now := r.Time.UnixNano()
+	if r.Time.IsZero() {
+		now = time.Now().UnixNano()
+	}
+	if end := c.windowEnd.Load(); now >= end && c.windowEnd.CompareAndSwap(end, now+int64(s.interval)) {
+		c.n.Store(0)
+	}
+
+	n := c.n.Add(1)
+	if n <= s.first || (s.thereafter > 0 && (n-s.first)%s.thereafter == 0) {
+		return true
+	}
+	sampledOut.Add(1)
+	return false
+}
+
+func sampleIndex(level slog.Level, msg string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte{byte(level)})
+	h.Write([]byte(msg))
+	return h.Sum32() % sampleCounters
+}
@@ -0,0 +1,68 @@
+package logging
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSamplerKeepsFirstThenEveryNth(t *testing.T) {
+	s := NewSampler(3, 5, time.Second)
+	now := time.Now()
+	var kept []int
+	for i := 1; i <= 20; i++ {
+		if s.Allow(slog.NewRecord(now, slog.LevelInfo, "hot", 0)) {
+			kept = append(kept, i)
+		}
+	}
+	want := []int{1, 2, 3, 8, 13, 18}
+	if len(kept) != len(want) {
+		t.Fatalf("kept %v, want %v", kept, want)
+	}
+	for i := range want {
+		if kept[i] != want[i] {
+			t.Fatalf("kept %v, want %v", kept, want)
+		}
+	}
+
+	if !s.Allow(slog.NewRecord(now, slog.LevelInfo, "other", 0)) {
+		t.Error("a different message should have its own count")
+	}
+	if !s.Allow(slog.NewRecord(now.Add(time.Second), slog.LevelInfo, "hot", 0)) {
+		t.Error("the count should restart in the next interval")
+	}
+}
+
+func TestSamplerAlwaysKeepsWarnings(t *testing.T) {
+	s := NewSampler(1, 0, time.Minute)
+	now := time.Now()
+	for _, lvl := range []slog.Level{slog.LevelWarn, slog.LevelError} {
+		for range 10 {
+			if !s.Allow(slog.NewRecord(now, lvl, "failing", 0)) {
+				t.Fatalf("%s record was dropped", lvl)
+			}
+		}
+	}
+}
+
+func TestSetSamplerCountsDropped(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Setup("info", "json", &buf); err != nil {
+		t.Fatal(err)
+	}
+	SetSampler(NewSampler(2, 0, time.Minute))
+	t.Cleanup(func() { SetSampler(nil) })
+
+	before := SampledOut()
+	for range 5 {
+		slog.With("device", "d1").Info("heartbeat")
+	}
+	if n := strings.Count(buf.String(), "heartbeat"); n != 2 {
+		t.Errorf("logged %d records, want 2:\n%s", n, buf.String())
+	}
+	if got := SampledOut() - before; got != 3 {
+		t.Errorf("SampledOut() grew by %d, want 3", got)
+	}
+}
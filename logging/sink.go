@@ -0,0 +1,110 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"strings"
+)
+
+type Sink struct {
+	Writer io.Writer
+	Format string
+	// Level can only raise the global and component levels.
+	Level string
+}
+
+var Formats = []string{"json", "text", "logfmt", "pretty", "gcp", "ecs"}
+
+// Levels are enforced by swapHandler and sinksHandler.
+func newFormatHandler(format string, w io.Writer) (slog.Handler, error) {
+	opts := &slog.HandlerOptions{Level: slog.Level(math.MinInt)}
+	switch strings.ToLower(format) {
+	case "json":
+		return slog.NewJSONHandler(w, opts), nil
+	case "text":
+		return slog.NewTextHandler(w, opts), nil
+	case "logfmt":
+		opts.ReplaceAttr = logfmtAttr
+		return slog.NewTextHandler(w, opts), nil
+	case "pretty":
+		return newPrettyHandler(w, useColor(w)), nil
+	case "gcp":
+		opts.ReplaceAttr = gcpAttr
+		return slog.NewJSONHandler(w, opts), nil
+	case "ecs":
+		opts.ReplaceAttr = ecsAttr
+		return slog.NewJSONHandler(w, opts).WithAttrs([]slog.Attr{slog.String("ecs.version", ecsVersion)}), nil
+	default:
+		return nil, fmt.Errorf("unknown log format %q (valid: %s)", format, strings.Join(Formats, ", "))
+	}
+}
+
+type sinkHandler struct {
+	handler slog.Handler
+	floor   slog.Level
+}
+
+type sinksHandler []sinkHandler
+
+func newSinksHandler(sinks []Sink) (slog.Handler, error) {
+	if len(sinks) == 0 {
+		return nil, errors.New("no log sinks")
+	}
+	out := make(sinksHandler, len(sinks))
+	for i, s := range sinks {
+		h, err := newFormatHandler(s.Format, s.Writer)
+		if err != nil {
+			return nil, err
+		}
+		floor := slog.Level(math.MinInt)
+		if s.Level != "" {
+			if floor, err = parseLevel(s.Level); err != nil {
+				return nil, err
+			}
+		}
+		out[i] = sinkHandler{handler: h, floor: floor}
+	}
+	if len(out) == 1 && out[0].floor == math.MinInt {
+		return out[0].handler, nil
+	}
+	return out, nil
+}
+
+func (h sinksHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	for _, s := range h {
+		if l >= s.floor && s.handler.Enabled(ctx, l) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h sinksHandler) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, s := range h {
+		if r.Level >= s.floor && s.handler.Enabled(ctx, r.Level) {
+			errs = append(errs, s.handler.Handle(ctx, r.Clone()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (h sinksHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	out := make(sinksHandler, len(h))
+	for i, s := range h {
+		out[i] = sinkHandler{handler: s.handler.WithAttrs(attrs), floor: s.floor}
+	}
+	return out
+}
+
+func (h sinksHandler) WithGroup(name string) slog.Handler {
+	out := make(sinksHandler, len(h))
+	for i, s := range h {
+		out[i] = sinkHandler{handler: s.handler.WithGroup(name), floor: s.floor}
+	}
+	return out
+}
@@ -0,0 +1,84 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestFormats(t *testing.T) {
+	cases := []struct {
+		format string
+		want   []string
+	}{
+		{"logfmt", []string{"ts=", "level=warn", `msg="disk low"`, "free=3"}},
+		{"pretty", []string{" WRN disk low", "free=3", "req.path=/v1"}},
+		{"gcp", []string{`"timestamp":`, `"severity":"WARNING"`, `"message":"disk low"`}},
+		{"ecs", []string{`"@timestamp":`, `"log.level":"warn"`, `"message":"disk low"`, `"ecs.version":"8.11.0"`, `"error.message":"full"`}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			h, err := newFormatHandler(tc.format, &buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			slog.New(h).Warn("disk low", "free", 3, "error", errors.New("full"), slog.Group("req", "path", "/v1"))
+			for _, want := range tc.want {
+				if !strings.Contains(buf.String(), want) {
+					t.Errorf("expected %s in output, got: %s", want, buf.String())
+				}
+			}
+		})
+	}
+}
+
+func TestPrettyWithoutColorHasNoEscapes(t *testing.T) {
+	var buf bytes.Buffer
+	slog.New(newPrettyHandler(&buf, false)).With("a", "b c").Info("hello")
+	if strings.Contains(buf.String(), "\x1b") || !strings.Contains(buf.String(), `INF hello a="b c"`) {
+		t.Fatalf("unexpected output: %q", buf.String())
+	}
+	buf.Reset()
+	slog.New(newPrettyHandler(&buf, true)).Error("hello")
+	if !strings.Contains(buf.String(), ansiRed+"ERR"+ansiReset) {
+		t.Fatalf("expected a red level, got: %q", buf.String())
+	}
+}
+
+func TestSetupSinksWithLevels(t *testing.T) {
+	var stdout, file bytes.Buffer
+	err := SetupSinks("debug",
+		Sink{Writer: &stdout, Format: "text", Level: "info"},
+		Sink{Writer: &file, Format: "json"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	slog.With("component", "test").Debug("detail")
+	slog.Info("summary")
+
+	if strings.Contains(stdout.String(), "detail") || !strings.Contains(stdout.String(), "summary") {
+		t.Errorf("stdout sink should only get info records, got: %s", stdout.String())
+	}
+	lines := strings.Split(strings.TrimSpace(file.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("file sink should get both records, got: %s", file.String())
+	}
+	var rec map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &rec); err != nil || rec["component"] != "test" {
+		t.Errorf("expected the first record with its attributes, got: %s", lines[0])
+	}
+}
+
+func TestSetupSinksRejectsUnknownFormat(t *testing.T) {
+	if err := SetupSinks("info", Sink{Writer: &bytes.Buffer{}, Format: "xml"}); err == nil {
+		t.Fatal("expected error for invalid format")
+	}
+	if err := SetupSinks("info"); err == nil {
+		t.Fatal("expected error without sinks")
+	}
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	_ "embed"
+	"errors"
 	"flag"
 	"fmt"
 	"log/slog"
@@ -11,11 +12,17 @@ import (
 	"strings"
 	"sync"
 	"syscall"
+	"time"
 
+	"github.com/flockiot/flock-api/admin"
 	"github.com/flockiot/flock-api/config"
 	"github.com/flockiot/flock-api/database"
+	"github.com/flockiot/flock-api/feature"
+	"github.com/flockiot/flock-api/health"
 	"github.com/flockiot/flock-api/logging"
+	"github.com/flockiot/flock-api/metrics"
 	"github.com/flockiot/flock-api/target"
+	"github.com/flockiot/flock-api/tracing"
 	"github.com/flockiot/flock-api/version"
 )
 
@@ -25,20 +32,40 @@ var embeddedVersion string
 func main() {
 	version.Value = strings.TrimSpace(embeddedVersion)
 
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		os.Exit(runConfigCommand(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrateCommand(os.Args[2:]))
+	}
+
 	targetFlag := flag.String("target", "", "comma-separated list of targets to run, or 'all'")
-	migrateOnly := flag.Bool("migrate-only", false, "run database migrations and exit")
+	migrateOnly := flag.Bool("migrate-only", false, "run database migrations and exit, even with auto-migrate disabled")
+	configFile := flag.String("config", os.Getenv("FLOCK_CONFIG"), configFlagUsage)
+	checkConfig := flag.Bool("check-config", false, "validate the configuration and exit without connecting to anything")
+	overrides := config.BindFlags(flag.CommandLine)
 	flag.Parse()
 
-	cfg, err := config.Load()
+	loadOpts := config.Options{File: *configFile, Flags: overrides}
+	cfg, _, err := config.LoadWithOptions(loadOpts)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error loading config: %v\n", err)
 		os.Exit(1)
 	}
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if *checkConfig {
+		fmt.Println("configuration OK")
+		return
+	}
 
-	if err := logging.Setup(cfg.Log.Level, cfg.Log.Format, os.Stdout); err != nil {
+	if err := setupLogging(cfg.Log); err != nil {
 		fmt.Fprintf(os.Stderr, "error setting up logging: %v\n", err)
 		os.Exit(1)
 	}
+	feature.Set(cfg.Features.Enabled)
 
 	slog.Info("flock-api configured",
 		"server_host", cfg.Server.Host,
@@ -47,10 +74,48 @@ func main() {
 		"log_format", cfg.Log.Format,
 	)
 
+	// Deferred first so it runs last, after the cleanup deferred below.
+	exitCode := 0
+	defer func() {
+		if exitCode != 0 {
+			os.Exit(exitCode)
+		}
+	}()
+
 	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer cancel()
+	// Restore default signal handling once shutdown starts, so a second
+	// signal kills the process immediately.
+	go func() {
+		<-ctx.Done()
+		cancel()
+	}()
+
+	reloader := config.NewReloader(cfg, loadOpts)
+	config.Subscribe(ctx, "log.", func(cfg *config.Config) {
+		if err := setupLogging(cfg.Log); err != nil {
+			slog.Error("reconfiguring logging failed", "error", err)
+		}
+	})
+	config.Subscribe(ctx, "features.", func(cfg *config.Config) {
+		feature.Set(cfg.Features.Enabled)
+	})
+	go reloadOnHangup(ctx, reloader)
+
+	shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing)
+	if err != nil {
+		slog.Error("failed to set up tracing", "error", err)
+		os.Exit(1)
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			slog.Error("flushing traces failed", "error", err)
+		}
+	}()
 
-	pool, err := database.Connect(ctx, cfg.Postgres.DSN)
+	pool, err := database.Open(ctx, cfg.Postgres)
 	if err != nil {
 		slog.Error("failed to connect to database", "error", err)
 		os.Exit(1)
@@ -58,17 +123,42 @@ func main() {
 	defer pool.Close()
 	slog.Info("database connected")
 
-	slog.Info("running database migrations")
-	if err := database.Migrate(cfg.Postgres.DSN); err != nil {
-		slog.Error("failed to run migrations", "error", err)
+	if err := metrics.RegisterPool(pool); err != nil {
+		slog.Error("failed to register pool metrics", "error", err)
 		os.Exit(1)
 	}
-	slog.Info("database migrations complete")
+
+	if cfg.Postgres.AutoMigrate || *migrateOnly {
+		slog.Info("running database migrations")
+		if err := database.Migrate(cfg.Postgres.MigrationURL()); err != nil {
+			slog.Error("failed to run migrations", "error", err)
+			os.Exit(1)
+		}
+		slog.Info("database migrations complete")
+	}
 
 	if *migrateOnly {
 		return
 	}
 
+	if !cfg.Postgres.AutoMigrate {
+		if err := database.WaitForSchema(ctx, pool, cfg.Postgres.SchemaWait); err != nil {
+			slog.Error("refusing to start", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	var replicas *database.Replicas
+	if len(cfg.Postgres.ReplicaDSNs) > 0 {
+		replicas, err = database.OpenReplicas(ctx, pool, cfg.Postgres)
+		if err != nil {
+			slog.Error("failed to set up read replicas", "error", err)
+			os.Exit(1)
+		}
+		defer replicas.Close()
+		go replicas.Run(ctx)
+	}
+
 	targetValue := *targetFlag
 	if targetValue == "" {
 		targetValue = os.Getenv("FLOCK_TARGET")
@@ -86,26 +176,100 @@ func main() {
 		os.Exit(1)
 	}
 
+	checks := health.NewRegistry()
+	checks.Register("database", health.Database(pool))
+
 	deps := &target.Deps{
-		Config: cfg,
-		DB:     pool,
+		Config:   cfg,
+		DB:       pool,
+		Replicas: replicas,
+		Health:   checks,
 	}
 
 	slog.Info("flock-api starting", "targets", targetValue)
 
+	sup := target.NewSupervisor(cfg.Supervisor, deps)
+
 	var wg sync.WaitGroup
-	for name, fn := range targets {
+	if cfg.Server.AdminPort != 0 {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			if err := fn(ctx, deps); err != nil {
-				slog.Error("target failed", "target", name, "error", err)
+			if err := admin.Start(ctx, cfg, sup, reloader); err != nil {
+				slog.Error("admin server failed", "error", err)
 			}
 		}()
 	}
 
-	slog.Info("all targets started", "count", len(targets))
-
-	<-ctx.Done()
+	err = sup.Run(ctx, targets)
+	if errors.Is(err, target.ErrShutdownTimeout) {
+		// Stuck targets may hold connections that would block pool.Close.
+		slog.Error("forcing exit", "error", err)
+		os.Exit(1)
+	}
+	if err != nil {
+		slog.Error("shutting down", "error", err)
+		exitCode = 1
+	}
+	cancel()
 	wg.Wait()
 }
+
+func reloadOnHangup(ctx context.Context, reloader *config.Reloader) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			slog.Info("reloading configuration")
+			if _, err := reloader.Reload(); err != nil {
+				slog.Error("configuration reload failed", "error", err)
+			}
+		}
+	}
+}
+
+// logFile is closed when a reload replaces it.
+var logFile *logging.RotatingFile
+
+func setupLogging(cfg config.LogConfig) error {
+	redactor, err := logging.NewRedactor(cfg.RedactKeys, cfg.RedactValues)
+	if err != nil {
+		return err
+	}
+
+	var sinks []logging.Sink
+	if cfg.Stdout {
+		sinks = append(sinks, logging.Sink{Writer: os.Stdout, Format: cfg.Format, Level: cfg.StdoutLevel})
+	}
+	var file *logging.RotatingFile
+	if cfg.File != "" {
+		file, err = logging.OpenRotatingFile(cfg.File, int64(cfg.FileMaxSizeMB)<<20, cfg.FileMaxBackups)
+		if err != nil {
+			return err
+		}
+		sinks = append(sinks, logging.Sink{Writer: file, Format: cfg.FileFormat, Level: cfg.FileLevel})
+	}
+
+	var sampler *logging.Sampler
+	if cfg.SampleFirst > 0 {
+		sampler = logging.NewSampler(cfg.SampleFirst, cfg.SampleThereafter, cfg.SampleInterval)
+	}
+
+	logging.SetRedactor(redactor)
+	logging.SetSampler(sampler)
+	if err := logging.SetupSinks(cfg.Level, sinks...); err != nil {
+		if file != nil {
+			file.Close()
+		}
+		return err
+	}
+	if logFile != nil {
+		logFile.Close()
+	}
+	logFile = file
+	return logging.SetComponentLevels(cfg.Levels)
+}
@@ -0,0 +1,118 @@
+package metrics
+
+import (
+	"net/http"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/flockiot/flock-api/logging"
+	"github.com/flockiot/flock-api/version"
+)
+
+const namespace = "flock"
+
+// Registry is separate from the prometheus default registry so only
+// collectors registered here are exported.
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "HTTP requests handled, by route pattern and status code.",
+	}, []string{"method", "route", "code"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "HTTP request latency, by route pattern.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	targetUp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "target",
+		Name:      "up",
+		Help:      "Whether the target is running (1) or stopped (0).",
+	}, []string{"target"})
+
+	targetRestarts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "target",
+		Name:      "restarts_total",
+		Help:      "Times the target has been restarted after exiting.",
+	}, []string{"target"})
+
+	logSampledOut = prometheus.NewCounterFunc(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "log",
+		Name:      "records_sampled_out_total",
+		Help:      "Log records dropped by sampling.",
+	}, func() float64 { return float64(logging.SampledOut()) })
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		buildInfoCollector{},
+		httpRequests,
+		httpDuration,
+		targetUp,
+		targetRestarts,
+		logSampledOut,
+	)
+}
+
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// route must be the router's pattern, not the raw path, to bound cardinality.
+func ObserveHTTPRequest(method, route string, code int, d time.Duration) {
+	httpRequests.WithLabelValues(method, route, strconv.Itoa(code)).Inc()
+	httpDuration.WithLabelValues(method, route).Observe(d.Seconds())
+}
+
+func SetTargetUp(name string, up bool) {
+	v := 0.0
+	if up {
+		v = 1
+	}
+	targetUp.WithLabelValues(name).Set(v)
+	// Touch the counter so every target is exported with zero restarts.
+	targetRestarts.WithLabelValues(name)
+}
+
+func IncTargetRestarts(name string) {
+	targetRestarts.WithLabelValues(name).Inc()
+}
+
+func RegisterPool(pool *pgxpool.Pool) error {
+	return Registry.Register(&poolCollector{pool: pool})
+}
+
+// main sets the version after package initialisation.
+type buildInfoCollector struct{}
+
+var buildInfoDesc = prometheus.NewDesc(
+	prometheus.BuildFQName(namespace, "", "build_info"),
+	"Always 1; labelled with the running build.",
+	[]string{"version", "goversion"}, nil,
+)
+
+func (buildInfoCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- buildInfoDesc
+}
+
+func (buildInfoCollector) Collect(ch chan<- prometheus.Metric) {
+	ch <- prometheus.MustNewConstMetric(buildInfoDesc, prometheus.GaugeValue, 1, version.Get(), runtime.Version())
+}
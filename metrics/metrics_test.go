@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestObserveHTTPRequest(t *testing.T) {
+	before := testutil.ToFloat64(httpRequests.WithLabelValues("GET", "/v1/things/{id}", "200"))
+	ObserveHTTPRequest(http.MethodGet, "/v1/things/{id}", http.StatusOK, 25*time.Millisecond)
+
+	if got := testutil.ToFloat64(httpRequests.WithLabelValues("GET", "/v1/things/{id}", "200")); got != before+1 {
+		t.Fatalf("expected counter %v, got %v", before+1, got)
+	}
+}
+
+func TestTargetGauges(t *testing.T) {
+	SetTargetUp("test-target", true)
+	if got := testutil.ToFloat64(targetUp.WithLabelValues("test-target")); got != 1 {
+		t.Fatalf("expected up 1, got %v", got)
+	}
+	if got := testutil.ToFloat64(targetRestarts.WithLabelValues("test-target")); got != 0 {
+		t.Fatalf("expected 0 restarts, got %v", got)
+	}
+
+	IncTargetRestarts("test-target")
+	SetTargetUp("test-target", false)
+	if got := testutil.ToFloat64(targetUp.WithLabelValues("test-target")); got != 0 {
+		t.Fatalf("expected up 0, got %v", got)
+	}
+	if got := testutil.ToFloat64(targetRestarts.WithLabelValues("test-target")); got != 1 {
+		t.Fatalf("expected 1 restart, got %v", got)
+	}
+}
+
+func TestPoolCollector(t *testing.T) {
+	// The pool connects lazily, so stats are available without a database.
+	pool, err := pgxpool.New(context.Background(), "postgres://flock@localhost:1/flock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	c := &poolCollector{pool: pool}
+	if n := testutil.CollectAndCount(c); n != 9 {
+		t.Fatalf("expected 9 pool metrics, got %d", n)
+	}
+	expected := `
+# HELP flock_db_pool_acquired_connections Connections currently checked out of the pool.
+# TYPE flock_db_pool_acquired_connections gauge
+flock_db_pool_acquired_connections 0
+`
+	if err := testutil.CollectAndCompare(c, strings.NewReader(expected), "flock_db_pool_acquired_connections"); err != nil {
+		t.Fatal(err)
+	}
+}
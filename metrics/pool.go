@@ -0,0 +1,54 @@
+package metrics
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func poolDesc(name, help string) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName(namespace, "db_pool", name), help, nil, nil)
+}
+
+var (
+	poolAcquiredDesc    = poolDesc("acquired_connections", "Connections currently checked out of the pool.")
+	poolIdleDesc        = poolDesc("idle_connections", "Idle connections in the pool.")
+	poolTotalDesc       = poolDesc("total_connections", "Open connections, including ones being established.")
+	poolMaxDesc         = poolDesc("max_connections", "Maximum size of the pool.")
+	poolAcquiresDesc    = poolDesc("acquires_total", "Successful connection acquisitions.")
+	poolEmptyDesc       = poolDesc("empty_acquires_total", "Acquisitions that had to wait because the pool was empty.")
+	poolCanceledDesc    = poolDesc("canceled_acquires_total", "Acquisitions canceled by their context.")
+	poolWaitDesc        = poolDesc("acquire_wait_seconds_total", "Time spent waiting for a connection when the pool was empty.")
+	poolAcquireTimeDesc = poolDesc("acquire_duration_seconds_total", "Time spent in successful acquisitions.")
+)
+
+type poolCollector struct {
+	pool *pgxpool.Pool
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{
+		poolAcquiredDesc, poolIdleDesc, poolTotalDesc, poolMaxDesc,
+		poolAcquiresDesc, poolEmptyDesc, poolCanceledDesc, poolWaitDesc, poolAcquireTimeDesc,
+	} {
+		ch <- d
+	}
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.pool.Stat()
+	gauge := func(d *prometheus.Desc, v float64) {
+		ch <- prometheus.MustNewConstMetric(d, prometheus.GaugeValue, v)
+	}
+	counter := func(d *prometheus.Desc, v float64) {
+		ch <- prometheus.MustNewConstMetric(d, prometheus.CounterValue, v)
+	}
+	gauge(poolAcquiredDesc, float64(s.AcquiredConns()))
+	gauge(poolIdleDesc, float64(s.IdleConns()))
+	gauge(poolTotalDesc, float64(s.TotalConns()))
+	gauge(poolMaxDesc, float64(s.MaxConns()))
+	counter(poolAcquiresDesc, float64(s.AcquireCount()))
+	counter(poolEmptyDesc, float64(s.EmptyAcquireCount()))
+	counter(poolCanceledDesc, float64(s.CanceledAcquireCount()))
+	counter(poolWaitDesc, s.EmptyAcquireWaitTime().Seconds())
+	counter(poolAcquireTimeDesc, s.AcquireDuration().Seconds())
+}
@@ -0,0 +1,161 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/flockiot/flock-api/auth"
+	"github.com/flockiot/flock-api/config"
+)
+
+type KeyFunc func(ctx context.Context, p *auth.Principal) string
+
+func byKey(_ context.Context, p *auth.Principal) string {
+	return "key:" + p.KeyID
+}
+
+func byOrganization(_ context.Context, p *auth.Principal) string {
+	return "org:" + p.OrganizationID
+}
+
+// byDevice falls back to the API key for requests without a certificate.
+func byDevice(ctx context.Context, p *auth.Principal) string {
+	if id, ok := auth.ClientIdentityFromContext(ctx); ok {
+		return "device:" + id.Fingerprint
+	}
+	return byKey(ctx, p)
+}
+
+func keyFunc(name string) (KeyFunc, error) {
+	switch name {
+	case "key":
+		return byKey, nil
+	case "org":
+		return byOrganization, nil
+	case "device":
+		return byDevice, nil
+	default:
+		return nil, fmt.Errorf("unknown rate limit key %q (valid: key, org, device)", name)
+	}
+}
+
+type Limiter struct {
+	store  Store
+	policy atomic.Pointer[policy]
+}
+
+type policy struct {
+	key         KeyFunc
+	tiers       map[string]Limit
+	defaultTier string
+	orgTiers    map[string]string
+}
+
+func New(cfg config.RateLimitConfig, store Store) (*Limiter, error) {
+	l := &Limiter{store: store}
+	if err := l.Update(cfg); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Update keeps existing buckets. Enabled and Store are ignored.
+func (l *Limiter) Update(cfg config.RateLimitConfig) error {
+	key, err := keyFunc(cfg.KeyBy)
+	if err != nil {
+		return err
+	}
+	p := &policy{
+		key:         key,
+		tiers:       make(map[string]Limit, len(cfg.Tiers)),
+		defaultTier: cfg.DefaultTier,
+		orgTiers:    cfg.OrgTiers,
+	}
+	for name, spec := range cfg.Tiers {
+		limit, err := ParseLimit(spec)
+		if err != nil {
+			return fmt.Errorf("tier %q: %w", name, err)
+		}
+		p.tiers[name] = limit
+	}
+	if _, ok := p.tiers[cfg.DefaultTier]; !ok {
+		return fmt.Errorf("default rate limit tier %q is not defined", cfg.DefaultTier)
+	}
+	for org, tier := range cfg.OrgTiers {
+		if _, ok := p.tiers[tier]; !ok {
+			return fmt.Errorf("organization %s uses undefined rate limit tier %q", org, tier)
+		}
+	}
+	l.policy.Store(p)
+	return nil
+}
+
+func (p *policy) limitFor(principal *auth.Principal) Limit {
+	if tier, ok := p.orgTiers[principal.OrganizationID]; ok {
+		return p.tiers[tier]
+	}
+	return p.tiers[p.defaultTier]
+}
+
+type Decision struct {
+	Result
+	Limit Limit
+}
+
+func (d *Decision) RetryAfter() time.Duration {
+	return untilNext(d.Remaining, d.Limit)
+}
+
+// Take returns nil for requests without a principal and admin requests, which
+// are not limited.
+func (l *Limiter) Take(ctx context.Context) (*Decision, error) {
+	p, ok := auth.FromContext(ctx)
+	if !ok || p.Admin {
+		return nil, nil
+	}
+	pol := l.policy.Load()
+	limit := pol.limitFor(p)
+	res, err := l.store.Take(ctx, pol.key(ctx, p), limit)
+	if err != nil {
+		return nil, err
+	}
+	return &Decision{Result: res, Limit: limit}, nil
+}
+
+// Middleware must run after authentication. If the store fails the request
+// is let through.
+func (l *Limiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		d, err := l.Take(r.Context())
+		if err != nil {
+			slog.ErrorContext(r.Context(), "rate limit check failed", "error", err)
+		}
+		if d == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		h := w.Header()
+		h.Set("RateLimit-Limit", strconv.Itoa(d.Limit.Burst))
+		h.Set("RateLimit-Remaining", strconv.Itoa(int(math.Floor(d.Remaining))))
+		h.Set("RateLimit-Reset", seconds(untilFull(d.Remaining, d.Limit).Seconds()))
+		h.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%s", d.Limit.Burst, seconds(d.Limit.RefillTime().Seconds())))
+		if !d.Allowed {
+			h.Set("Retry-After", seconds(d.RetryAfter().Seconds()))
+			http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// seconds rounds up so clients that wait that long are not rejected again.
+func seconds(s float64) string {
+	return strconv.Itoa(int(math.Ceil(s)))
+}
@@ -0,0 +1,154 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/flockiot/flock-api/auth"
+	"github.com/flockiot/flock-api/config"
+)
+
+type failingStore struct{}
+
+func (failingStore) Take(context.Context, string, Limit) (Result, error) {
+	return Result{}, errors.New("store down")
+}
+
+func testLimiter(t *testing.T, store Store) http.Handler {
+	t.Helper()
+	l, err := New(config.RateLimitConfig{
+		KeyBy:       "key",
+		DefaultTier: "default",
+		Tiers:       map[string]string{"default": "60/1m:2", "gold": "600/1m:100"},
+		OrgTiers:    map[string]string{"org-gold": "gold"},
+	}, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l.Middleware(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+}
+
+func do(h http.Handler, p *auth.Principal) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/v1/organizations/x", nil)
+	if p != nil {
+		req = req.WithContext(auth.WithPrincipal(req.Context(), p))
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+func TestMiddlewareLimitsAndSetsHeaders(t *testing.T) {
+	h := testLimiter(t, NewMemoryStore())
+	p := &auth.Principal{KeyID: "key-1", OrganizationID: "org-1"}
+
+	w := do(h, p)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", w.Code)
+	}
+	for header, want := range map[string]string{
+		"RateLimit-Limit":     "2",
+		"RateLimit-Remaining": "1",
+		"RateLimit-Reset":     "1",
+		"RateLimit-Policy":    "2;w=2",
+	} {
+		if got := w.Header().Get(header); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+
+	do(h, p)
+	w = do(h, p)
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429, got %d", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "1" {
+		t.Errorf("Retry-After = %q, want %q", got, "1")
+	}
+
+	if w := do(h, &auth.Principal{KeyID: "key-2", OrganizationID: "org-1"}); w.Code != http.StatusNoContent {
+		t.Fatalf("another key should not share the bucket, got %d", w.Code)
+	}
+}
+
+func TestMiddlewareOrganizationTier(t *testing.T) {
+	h := testLimiter(t, NewMemoryStore())
+	w := do(h, &auth.Principal{KeyID: "key-1", OrganizationID: "org-gold"})
+	if got := w.Header().Get("RateLimit-Limit"); got != "100" {
+		t.Fatalf("RateLimit-Limit = %q, want the gold tier burst", got)
+	}
+}
+
+func TestMiddlewareExemptsAdmin(t *testing.T) {
+	h := testLimiter(t, NewMemoryStore())
+	for range 5 {
+		if w := do(h, &auth.Principal{Admin: true}); w.Code != http.StatusNoContent {
+			t.Fatalf("admin should not be limited, got %d", w.Code)
+		}
+	}
+}
+
+func TestMiddlewareFailsOpen(t *testing.T) {
+	h := testLimiter(t, failingStore{})
+	if w := do(h, &auth.Principal{KeyID: "key-1"}); w.Code != http.StatusNoContent {
+		t.Fatalf("store errors should not reject requests, got %d", w.Code)
+	}
+}
+
+func TestNewRejectsUndefinedTiers(t *testing.T) {
+	cases := []config.RateLimitConfig{
+		{KeyBy: "key", DefaultTier: "missing", Tiers: map[string]string{"default": "1/s"}},
+		{KeyBy: "key", DefaultTier: "default", Tiers: map[string]string{"default": "1/s"}, OrgTiers: map[string]string{"org": "gold"}},
+		{KeyBy: "ip", DefaultTier: "default", Tiers: map[string]string{"default": "1/s"}},
+		{KeyBy: "key", DefaultTier: "default", Tiers: map[string]string{"default": "fast"}},
+	}
+	for _, cfg := range cases {
+		if _, err := New(cfg, NewMemoryStore()); err == nil {
+			t.Errorf("New(%+v) should fail", cfg)
+		}
+	}
+}
+
+func TestUpdateReplacesTiers(t *testing.T) {
+	cfg := config.RateLimitConfig{KeyBy: "key", DefaultTier: "default", Tiers: map[string]string{"default": "60/1m:2"}}
+	l, err := New(cfg, NewMemoryStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := l.Middleware(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	p := &auth.Principal{KeyID: "key-1"}
+
+	if err := l.Update(config.RateLimitConfig{KeyBy: "key", DefaultTier: "missing"}); err == nil {
+		t.Fatal("Update should reject an undefined default tier")
+	}
+	if got := do(h, p).Header().Get("RateLimit-Limit"); got != "2" {
+		t.Fatalf("RateLimit-Limit = %q after a failed update, want the old tier", got)
+	}
+
+	cfg.Tiers = map[string]string{"default": "600/1m:50"}
+	if err := l.Update(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if got := do(h, p).Header().Get("RateLimit-Limit"); got != "50" {
+		t.Fatalf("RateLimit-Limit = %q, want the updated tier", got)
+	}
+}
+
+func TestByDeviceUsesClientCertificate(t *testing.T) {
+	p := &auth.Principal{KeyID: "key-1"}
+	req := httptest.NewRequest(http.MethodGet, "/v1/organizations/x", nil)
+	if got := byDevice(req.Context(), p); got != "key:key-1" {
+		t.Fatalf("without a certificate: got %q, want the API key", got)
+	}
+	req = req.WithContext(auth.WithClientIdentity(req.Context(), &auth.ClientIdentity{Fingerprint: "ab12"}))
+	if got := byDevice(req.Context(), p); got != "device:ab12" {
+		t.Fatalf("with a certificate: got %q, want the fingerprint", got)
+	}
+}
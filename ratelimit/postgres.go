@@ -0,0 +1,23 @@
+package ratelimit
+
+import (
+	"context"
+
+	"github.com/flockiot/flock-api/repository"
+)
+
+type PostgresStore struct {
+	repo *repository.RateLimitRepository
+}
+
+func NewPostgresStore(repo *repository.RateLimitRepository) *PostgresStore {
+	return &PostgresStore{repo: repo}
+}
+
+func (s *PostgresStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	remaining, allowed, err := s.repo.Take(ctx, key, limit.Rate, limit.Burst)
+	if err != nil {
+		return Result{}, err
+	}
+	return Result{Allowed: allowed, Remaining: remaining}, nil
+}
@@ -0,0 +1,59 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/flockiot/flock-api/config"
+)
+
+// Limit is a token bucket refilled at Rate tokens per second.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// ParseLimit reads "<requests>/<duration>[:<burst>]", e.g. "600/1m:100".
+func ParseLimit(s string) (Limit, error) {
+	count, period, burst, err := config.ParseRate(s)
+	if err != nil {
+		return Limit{}, err
+	}
+	return Limit{Rate: float64(count) / period.Seconds(), Burst: burst}, nil
+}
+
+func (l Limit) RefillTime() time.Duration {
+	return time.Duration(float64(l.Burst) / l.Rate * float64(time.Second))
+}
+
+type Result struct {
+	Allowed   bool
+	Remaining float64
+}
+
+// Stores must refill and take atomically so concurrent requests cannot both
+// take the last token.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// take is shared by the stores so they agree on the arithmetic.
+func take(tokens float64, elapsed time.Duration, limit Limit) (float64, bool) {
+	tokens = math.Min(float64(limit.Burst), tokens+elapsed.Seconds()*limit.Rate)
+	if tokens >= 1 {
+		return tokens - 1, true
+	}
+	return tokens, false
+}
+
+func untilFull(remaining float64, limit Limit) time.Duration {
+	return time.Duration((float64(limit.Burst) - remaining) / limit.Rate * float64(time.Second))
+}
+
+func untilNext(remaining float64, limit Limit) time.Duration {
+	if remaining >= 1 {
+		return 0
+	}
+	return time.Duration((1 - remaining) / limit.Rate * float64(time.Second))
+}
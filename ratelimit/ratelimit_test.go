@@ -0,0 +1,78 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestParseLimit(t *testing.T) {
+	cases := []struct {
+		in   string
+		want Limit
+	}{
+		{"600/1m:100", Limit{Rate: 10, Burst: 100}},
+		{"10/s", Limit{Rate: 10, Burst: 10}},
+		{"3600/h:1", Limit{Rate: 1, Burst: 1}},
+	}
+	for _, tc := range cases {
+		got, err := ParseLimit(tc.in)
+		if err != nil {
+			t.Fatalf("ParseLimit(%q) error: %v", tc.in, err)
+		}
+		if got != tc.want {
+			t.Errorf("ParseLimit(%q) = %+v, want %+v", tc.in, got, tc.want)
+		}
+	}
+
+	for _, in := range []string{"", "600", "0/1m", "x/1m", "10/0s", "10/1m:0", "10/fortnight"} {
+		if _, err := ParseLimit(in); err == nil {
+			t.Errorf("ParseLimit(%q) should fail", in)
+		}
+	}
+}
+
+func TestMemoryStoreRefills(t *testing.T) {
+	now := time.Unix(0, 0)
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+	limit := Limit{Rate: 1, Burst: 2}
+	ctx := context.Background()
+
+	for i, want := range []bool{true, true, false} {
+		res, err := s.Take(ctx, "k", limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Allowed != want {
+			t.Fatalf("take %d: allowed = %v, want %v", i, res.Allowed, want)
+		}
+	}
+
+	now = now.Add(time.Second)
+	if res, _ := s.Take(ctx, "k", limit); !res.Allowed || res.Remaining != 0 {
+		t.Fatalf("after one second expected one token, got %+v", res)
+	}
+	if res, _ := s.Take(ctx, "other", limit); !res.Allowed || res.Remaining != 1 {
+		t.Fatalf("other keys should have their own bucket, got %+v", res)
+	}
+}
+
+func TestMemoryStoreSweepsFullBuckets(t *testing.T) {
+	now := time.Unix(0, 0)
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	_, _ = s.Take(ctx, "fast", Limit{Rate: 1, Burst: 1})
+	_, _ = s.Take(ctx, "slow", Limit{Rate: 0.001, Burst: 1})
+	now = now.Add(2 * sweepInterval)
+	_, _ = s.Take(ctx, "new", Limit{Rate: 1, Burst: 1})
+
+	if _, ok := s.buckets["fast"]; ok {
+		t.Error("refilled bucket should be swept")
+	}
+	if _, ok := s.buckets["slow"]; !ok {
+		t.Error("bucket still refilling should be kept")
+	}
+}
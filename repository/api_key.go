@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const apiKeyPrefix = "flk_"
+
+type APIKey struct {
+	ID             string
+	OrganizationID string
+	Name           string
+	CreatedAt      time.Time
+}
+
+type APIKeyRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewAPIKeyRepository(pool *pgxpool.Pool) *APIKeyRepository {
+	return &APIKeyRepository{pool: pool}
+}
+
+// Create returns the only copy of the plaintext token; the database stores
+// its SHA-256 hash.
+func (r *APIKeyRepository) Create(ctx context.Context, organizationID, name string) (*APIKey, string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, "", fmt.Errorf("generating api key: %w", err)
+	}
+	token := apiKeyPrefix + hex.EncodeToString(secret)
+
+	key := &APIKey{}
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx,
+			`INSERT INTO api_keys (organization_id, name, token_hash) VALUES ($1, $2, $3)
+			 RETURNING id, organization_id, name, created_at`,
+			organizationID, name, hashToken(token),
+		).Scan(&key.ID, &key.OrganizationID, &key.Name, &key.CreatedAt)
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, auditEntry{
+			OrganizationID: key.OrganizationID,
+			Action:         "api_key.create",
+			ResourceType:   "api_key",
+			ResourceID:     key.ID,
+			After:          map[string]any{"name": key.Name},
+		})
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("creating api key: %w", err)
+	}
+	return key, token, nil
+}
+
+func (r *APIKeyRepository) GetByToken(ctx context.Context, token string) (*APIKey, error) {
+	key := &APIKey{}
+	err := r.pool.QueryRow(ctx,
+		`SELECT k.id, k.organization_id, k.name, k.created_at FROM api_keys k
+		 JOIN organizations o ON o.id = k.organization_id
+		 WHERE k.token_hash = $1 AND k.revoked_at IS NULL AND o.deleted_at IS NULL`,
+		hashToken(token),
+	).Scan(&key.ID, &key.OrganizationID, &key.Name, &key.CreatedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("getting api key by token: %w", err)
+	}
+	return key, nil
+}
+
+func (r *APIKeyRepository) Revoke(ctx context.Context, id string) error {
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		key := &APIKey{}
+		err := tx.QueryRow(ctx,
+			`UPDATE api_keys SET revoked_at = now() WHERE id = $1 AND revoked_at IS NULL
+			 RETURNING id, organization_id, name, created_at`,
+			id,
+		).Scan(&key.ID, &key.OrganizationID, &key.Name, &key.CreatedAt)
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, auditEntry{
+			OrganizationID: key.OrganizationID,
+			Action:         "api_key.revoke",
+			ResourceType:   "api_key",
+			ResourceID:     key.ID,
+			Before:         map[string]any{"revoked": false},
+			After:          map[string]any{"revoked": true},
+		})
+	})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return fmt.Errorf("api key %w", ErrNotFound)
+		}
+		return fmt.Errorf("revoking api key: %w", err)
+	}
+	return nil
+}
+
+func hashToken(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestAPIKeyCreateAndGetByToken(t *testing.T) {
+	db := testPool(t)
+	orgs := NewOrganizationRepository(db.Pool, nil)
+	keys := NewAPIKeyRepository(db.Pool)
+
+	org, err := orgs.Create(context.Background(), "apikey-org")
+	if err != nil {
+		t.Fatalf("failed to create organization: %v", err)
+	}
+
+	key, token, err := keys.Create(context.Background(), org.ID, "ci")
+	if err != nil {
+		t.Fatalf("failed to create api key: %v", err)
+	}
+	if !strings.HasPrefix(token, "flk_") {
+		t.Fatalf("expected token with flk_ prefix, got %q", token)
+	}
+
+	found, err := keys.GetByToken(context.Background(), token)
+	if err != nil {
+		t.Fatalf("failed to get by token: %v", err)
+	}
+	if found == nil || found.ID != key.ID || found.OrganizationID != org.ID {
+		t.Fatalf("expected key %q for org %q, got %+v", key.ID, org.ID, found)
+	}
+}
+
+func TestAPIKeyRevoke(t *testing.T) {
+	db := testPool(t)
+	orgs := NewOrganizationRepository(db.Pool, nil)
+	keys := NewAPIKeyRepository(db.Pool)
+
+	org, err := orgs.Create(context.Background(), "revoke-org")
+	if err != nil {
+		t.Fatalf("failed to create organization: %v", err)
+	}
+	key, token, err := keys.Create(context.Background(), org.ID, "temp")
+	if err != nil {
+		t.Fatalf("failed to create api key: %v", err)
+	}
+
+	if err := keys.Revoke(context.Background(), key.ID); err != nil {
+		t.Fatalf("failed to revoke: %v", err)
+	}
+	found, err := keys.GetByToken(context.Background(), token)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if found != nil {
+		t.Fatal("expected nil for revoked key")
+	}
+}
+
+func TestAPIKeyGetByTokenUnknown(t *testing.T) {
+	db := testPool(t)
+	keys := NewAPIKeyRepository(db.Pool)
+
+	found, err := keys.GetByToken(context.Background(), "flk_unknown")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if found != nil {
+		t.Fatal("expected nil for unknown token")
+	}
+}
@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/flockiot/flock-api/audit"
+	"github.com/flockiot/flock-api/database"
+)
+
+type AuditEvent struct {
+	ID             int64
+	OccurredAt     time.Time
+	ActorType      string
+	ActorID        string
+	OrganizationID string
+	Action         string
+	ResourceType   string
+	ResourceID     string
+	Changes        json.RawMessage
+	RequestID      string
+	SourceIP       string
+}
+
+// Empty fields and zero times of an AuditFilter match everything.
+type AuditFilter struct {
+	OrganizationID string
+	ActorID        string
+	Action         string
+	ResourceType   string
+	ResourceID     string
+	Since          time.Time
+	Until          time.Time
+}
+
+type AuditRepository struct {
+	pool     *pgxpool.Pool
+	replicas *database.Replicas
+}
+
+func NewAuditRepository(pool *pgxpool.Pool, replicas *database.Replicas) *AuditRepository {
+	return &AuditRepository{pool: pool, replicas: replicas}
+}
+
+const auditColumns = `id, occurred_at, actor_type, actor_id, coalesce(organization_id::text, ''), action, resource_type, resource_id, changes, request_id, source_ip`
+
+const auditWhere = `WHERE ($1 = '' OR organization_id = NULLIF($1, '')::uuid)
+		   AND ($2 = '' OR actor_id = $2)
+		   AND ($3 = '' OR action = $3)
+		   AND ($4 = '' OR resource_type = $4)
+		   AND ($5 = '' OR resource_id = $5)
+		   AND ($6::timestamptz IS NULL OR occurred_at >= $6)
+		   AND ($7::timestamptz IS NULL OR occurred_at < $7)`
+
+func (f AuditFilter) args() []any {
+	var since, until *time.Time
+	if !f.Since.IsZero() {
+		since = &f.Since
+	}
+	if !f.Until.IsZero() {
+		until = &f.Until
+	}
+	return []any{f.OrganizationID, f.ActorID, f.Action, f.ResourceType, f.ResourceID, since, until}
+}
+
+func scanAuditEvent(row pgx.Row) (*AuditEvent, error) {
+	e := &AuditEvent{}
+	err := row.Scan(&e.ID, &e.OccurredAt, &e.ActorType, &e.ActorID, &e.OrganizationID, &e.Action,
+		&e.ResourceType, &e.ResourceID, &e.Changes, &e.RequestID, &e.SourceIP)
+	return e, err
+}
+
+func (r *AuditRepository) List(ctx context.Context, filter AuditFilter, limit, offset int) ([]*AuditEvent, error) {
+	args := append(filter.args(), limit, offset)
+	rows, err := reader(r.pool, r.replicas).Query(ctx,
+		`SELECT `+auditColumns+` FROM audit_events
+		 `+auditWhere+`
+		 ORDER BY id DESC
+		 LIMIT $8 OFFSET $9`,
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("listing audit events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []*AuditEvent
+	for rows.Next() {
+		e, err := scanAuditEvent(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning audit event: %w", err)
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+func (r *AuditRepository) Export(ctx context.Context, filter AuditFilter, fn func(*AuditEvent) error) error {
+	rows, err := r.pool.Query(ctx,
+		`SELECT `+auditColumns+` FROM audit_events
+		 `+auditWhere+`
+		 ORDER BY id`,
+		filter.args()...,
+	)
+	if err != nil {
+		return fmt.Errorf("exporting audit events: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		e, err := scanAuditEvent(rows)
+		if err != nil {
+			return fmt.Errorf("scanning audit event: %w", err)
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+type execer interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+// A nil Before or After means the resource did not exist.
+type auditEntry struct {
+	OrganizationID string
+	Action         string
+	ResourceType   string
+	ResourceID     string
+	Before         map[string]any
+	After          map[string]any
+}
+
+func recordAudit(ctx context.Context, q execer, e auditEntry) error {
+	m := audit.FromContext(ctx)
+	changes, err := json.Marshal(audit.Diff(e.Before, e.After))
+	if err != nil {
+		return fmt.Errorf("encoding audit changes: %w", err)
+	}
+	_, err = q.Exec(ctx,
+		`INSERT INTO audit_events
+		     (actor_type, actor_id, organization_id, action, resource_type, resource_id, changes, request_id, source_ip)
+		 VALUES ($1, $2, NULLIF($3, '')::uuid, $4, $5, $6, $7, $8, $9)`,
+		m.Actor.Type, m.Actor.ID, e.OrganizationID, e.Action, e.ResourceType, e.ResourceID,
+		changes, m.RequestID, m.SourceIP,
+	)
+	if err != nil {
+		return fmt.Errorf("recording audit event: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/flockiot/flock-api/audit"
+)
+
+func TestAuditRecordsOrganizationChanges(t *testing.T) {
+	db := testPool(t)
+	orgs := NewOrganizationRepository(db.Pool, nil)
+	audits := NewAuditRepository(db.Pool, nil)
+
+	ctx := audit.WithMetadata(context.Background(), audit.Metadata{
+		Actor:     audit.Actor{Type: audit.ActorAPIKey, ID: "key-audit"},
+		RequestID: "req-1",
+		SourceIP:  "198.51.100.1",
+	})
+	org, err := orgs.Create(ctx, "audit-org")
+	if err != nil {
+		t.Fatalf("failed to create: %v", err)
+	}
+	if _, err := orgs.Update(ctx, org.ID, "audit-org-renamed"); err != nil {
+		t.Fatalf("failed to update: %v", err)
+	}
+
+	events, err := audits.List(context.Background(), AuditFilter{OrganizationID: org.ID}, 10, 0)
+	if err != nil {
+		t.Fatalf("failed to list: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected 2 audit events, got %d", len(events))
+	}
+
+	update := events[0]
+	if update.Action != "organization.update" || update.ActorID != "key-audit" || update.RequestID != "req-1" {
+		t.Fatalf("unexpected audit event %+v", update)
+	}
+	var changes map[string]audit.Change
+	if err := json.Unmarshal(update.Changes, &changes); err != nil {
+		t.Fatalf("failed to decode changes: %v", err)
+	}
+	if c := changes["name"]; c.Before != "audit-org" || c.After != "audit-org-renamed" {
+		t.Fatalf("unexpected name change %+v", c)
+	}
+}
+
+func TestAuditExport(t *testing.T) {
+	db := testPool(t)
+	orgs := NewOrganizationRepository(db.Pool, nil)
+	audits := NewAuditRepository(db.Pool, nil)
+
+	org, err := orgs.Create(context.Background(), "audit-export-org")
+	if err != nil {
+		t.Fatalf("failed to create: %v", err)
+	}
+
+	var exported []*AuditEvent
+	err = audits.Export(context.Background(), AuditFilter{OrganizationID: org.ID}, func(e *AuditEvent) error {
+		exported = append(exported, e)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to export: %v", err)
+	}
+	if len(exported) != 1 || exported[0].ActorType != audit.ActorSystem {
+		t.Fatalf("expected one system audit event, got %d", len(exported))
+	}
+}
+
+func TestAuditEventsAppendOnly(t *testing.T) {
+	db := testPool(t)
+	orgs := NewOrganizationRepository(db.Pool, nil)
+
+	org, err := orgs.Create(context.Background(), "audit-append-only-org")
+	if err != nil {
+		t.Fatalf("failed to create: %v", err)
+	}
+	if _, err := db.Pool.Exec(context.Background(), `DELETE FROM audit_events WHERE organization_id = $1`, org.ID); err == nil {
+		t.Fatal("expected delete from audit_events to fail")
+	}
+}
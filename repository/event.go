@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	EventOrganizationCreated = "organization.created"
+	EventOrganizationUpdated = "organization.updated"
+	EventOrganizationDeleted = "organization.deleted"
+)
+
+type Event struct {
+	ID             int64
+	Type           string
+	OrganizationID string
+	FleetID        string
+	DeviceID       string
+	Data           json.RawMessage
+	CreatedAt      time.Time
+}
+
+// EventFilter selects events for a subscriber. Empty fields match everything.
+type EventFilter struct {
+	OrganizationID string
+	FleetID        string
+	DeviceID       string
+	Types          []string
+}
+
+func (f EventFilter) Match(e *Event) bool {
+	if f.OrganizationID != "" && e.OrganizationID != f.OrganizationID {
+		return false
+	}
+	if f.FleetID != "" && e.FleetID != f.FleetID {
+		return false
+	}
+	if f.DeviceID != "" && e.DeviceID != f.DeviceID {
+		return false
+	}
+	if len(f.Types) > 0 && !slices.Contains(f.Types, e.Type) {
+		return false
+	}
+	return true
+}
+
+type EventRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewEventRepository(pool *pgxpool.Pool) *EventRepository {
+	return &EventRepository{pool: pool}
+}
+
+func (r *EventRepository) Append(ctx context.Context, e *Event) error {
+	return appendEvent(ctx, r.pool, e)
+}
+
+func (r *EventRepository) ListSince(ctx context.Context, filter EventFilter, afterID int64, limit int) ([]*Event, error) {
+	rows, err := r.pool.Query(ctx,
+		`SELECT id, type, organization_id, coalesce(fleet_id::text, ''), coalesce(device_id::text, ''), data, created_at
+		 FROM events
+		 WHERE id > $1
+		   AND ($2 = '' OR organization_id = NULLIF($2, '')::uuid)
+		   AND ($3 = '' OR fleet_id = NULLIF($3, '')::uuid)
+		   AND ($4 = '' OR device_id = NULLIF($4, '')::uuid)
+		   AND (coalesce(cardinality($5::text[]), 0) = 0 OR type = ANY($5))
+		 ORDER BY id
+		 LIMIT $6`,
+		afterID, filter.OrganizationID, filter.FleetID, filter.DeviceID, filter.Types, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("listing events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []*Event
+	for rows.Next() {
+		e := &Event{}
+		if err := rows.Scan(&e.ID, &e.Type, &e.OrganizationID, &e.FleetID, &e.DeviceID, &e.Data, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scanning event: %w", err)
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+func (r *EventRepository) LatestID(ctx context.Context) (int64, error) {
+	var id int64
+	if err := r.pool.QueryRow(ctx, `SELECT coalesce(max(id), 0) FROM events`).Scan(&id); err != nil {
+		return 0, fmt.Errorf("getting latest event id: %w", err)
+	}
+	return id, nil
+}
+
+func (r *EventRepository) DeleteBefore(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.pool.Exec(ctx, `DELETE FROM events WHERE created_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("pruning events: %w", err)
+	}
+	return result.RowsAffected(), nil
+}
+
+type rowQuerier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// appendEvent takes q so an event can be recorded in the same transaction as
+// the change it describes.
+func appendEvent(ctx context.Context, q rowQuerier, e *Event) error {
+	data := e.Data
+	if data == nil {
+		data = json.RawMessage("{}")
+	}
+	err := q.QueryRow(ctx,
+		`INSERT INTO events (type, organization_id, fleet_id, device_id, data)
+		 VALUES ($1, $2, NULLIF($3, '')::uuid, NULLIF($4, '')::uuid, $5)
+		 RETURNING id, created_at`,
+		e.Type, e.OrganizationID, e.FleetID, e.DeviceID, data,
+	).Scan(&e.ID, &e.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("appending event: %w", err)
+	}
+	return nil
+}
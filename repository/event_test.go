@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestEventFilterMatch(t *testing.T) {
+	e := &Event{Type: "device.online", OrganizationID: "org", FleetID: "fleet", DeviceID: "dev"}
+
+	cases := []struct {
+		filter EventFilter
+		want   bool
+	}{
+		{EventFilter{}, true},
+		{EventFilter{OrganizationID: "org"}, true},
+		{EventFilter{OrganizationID: "other"}, false},
+		{EventFilter{OrganizationID: "org", FleetID: "fleet"}, true},
+		{EventFilter{OrganizationID: "org", FleetID: "other"}, false},
+		{EventFilter{DeviceID: "dev"}, true},
+		{EventFilter{DeviceID: "other"}, false},
+		{EventFilter{Types: []string{"device.offline", "device.online"}}, true},
+		{EventFilter{Types: []string{"device.offline"}}, false},
+	}
+	for _, tc := range cases {
+		if got := tc.filter.Match(e); got != tc.want {
+			t.Errorf("%+v.Match() = %v, want %v", tc.filter, got, tc.want)
+		}
+	}
+}
+
+func TestOrganizationMutationsAppendEvents(t *testing.T) {
+	db := testPool(t)
+	orgs := NewOrganizationRepository(db.Pool, nil)
+	events := NewEventRepository(db.Pool)
+
+	before, err := events.LatestID(context.Background())
+	if err != nil {
+		t.Fatalf("failed to get latest id: %v", err)
+	}
+
+	org, err := orgs.Create(context.Background(), "events-org")
+	if err != nil {
+		t.Fatalf("failed to create: %v", err)
+	}
+	if _, err := orgs.Update(context.Background(), org.ID, "events-org-renamed"); err != nil {
+		t.Fatalf("failed to update: %v", err)
+	}
+
+	list, err := events.ListSince(context.Background(), EventFilter{OrganizationID: org.ID}, before, 10)
+	if err != nil {
+		t.Fatalf("failed to list: %v", err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(list))
+	}
+	if list[0].Type != EventOrganizationCreated || list[1].Type != EventOrganizationUpdated {
+		t.Fatalf("unexpected event types %q, %q", list[0].Type, list[1].Type)
+	}
+}
+
+func TestEventListSinceFiltersByType(t *testing.T) {
+	db := testPool(t)
+	events := NewEventRepository(db.Pool)
+	orgID := "22222222-2222-2222-2222-222222222222"
+
+	for _, typ := range []string{"device.online", "device.offline"} {
+		if err := events.Append(context.Background(), &Event{Type: typ, OrganizationID: orgID}); err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+	}
+
+	list, err := events.ListSince(context.Background(), EventFilter{OrganizationID: orgID, Types: []string{"device.offline"}}, 0, 100)
+	if err != nil {
+		t.Fatalf("failed to list: %v", err)
+	}
+	for _, e := range list {
+		if e.Type != "device.offline" {
+			t.Fatalf("unexpected event type %q", e.Type)
+		}
+	}
+	if len(list) == 0 {
+		t.Fatal("expected at least one device.offline event")
+	}
+}
+
+func TestEventDeleteBefore(t *testing.T) {
+	db := testPool(t)
+	events := NewEventRepository(db.Pool)
+	orgID := "33333333-3333-3333-3333-333333333333"
+	cutoff := time.Now().Add(-time.Hour)
+
+	// Clear rows left behind by earlier runs so the count below is exact.
+	if _, err := events.DeleteBefore(context.Background(), cutoff); err != nil {
+		t.Fatalf("failed to prune: %v", err)
+	}
+
+	old := &Event{Type: "device.online", OrganizationID: orgID}
+	recent := &Event{Type: "device.offline", OrganizationID: orgID}
+	for _, e := range []*Event{old, recent} {
+		if err := events.Append(context.Background(), e); err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+	}
+	if _, err := db.Pool.Exec(context.Background(),
+		`UPDATE events SET created_at = $1 WHERE id = $2`, cutoff.Add(-time.Hour), old.ID); err != nil {
+		t.Fatalf("failed to backdate event: %v", err)
+	}
+
+	n, err := events.DeleteBefore(context.Background(), cutoff)
+	if err != nil {
+		t.Fatalf("failed to prune: %v", err)
+	}
+	if n != 1 {
+		t.Fatalf("expected 1 pruned event, got %d", n)
+	}
+
+	list, err := events.ListSince(context.Background(), EventFilter{OrganizationID: orgID}, old.ID-1, 10)
+	if err != nil {
+		t.Fatalf("failed to list: %v", err)
+	}
+	if len(list) != 1 || list[0].ID != recent.ID {
+		t.Fatalf("expected only event %d to remain, got %+v", recent.ID, list)
+	}
+}
@@ -2,11 +2,15 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/flockiot/flock-api/database"
 )
 
 type Organization struct {
@@ -17,21 +21,43 @@ type Organization struct {
 }
 
 type OrganizationRepository struct {
-	pool *pgxpool.Pool
+	pool     *pgxpool.Pool
+	replicas *database.Replicas
 }
 
-func NewOrganizationRepository(pool *pgxpool.Pool) *OrganizationRepository {
-	return &OrganizationRepository{pool: pool}
+func NewOrganizationRepository(pool *pgxpool.Pool, replicas *database.Replicas) *OrganizationRepository {
+	return &OrganizationRepository{pool: pool, replicas: replicas}
 }
 
 func (r *OrganizationRepository) Create(ctx context.Context, name string) (*Organization, error) {
+	if err := validateOrganizationName(name); err != nil {
+		return nil, err
+	}
 	org := &Organization{}
-	err := r.pool.QueryRow(ctx,
-		`INSERT INTO organizations (name) VALUES ($1)
-		 RETURNING id, name, created_at, updated_at`,
-		name,
-	).Scan(&org.ID, &org.Name, &org.CreatedAt, &org.UpdatedAt)
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx,
+			`INSERT INTO organizations (name) VALUES ($1)
+			 RETURNING id, name, created_at, updated_at`,
+			name,
+		).Scan(&org.ID, &org.Name, &org.CreatedAt, &org.UpdatedAt)
+		if err != nil {
+			return err
+		}
+		if err := appendEvent(ctx, tx, organizationEvent(EventOrganizationCreated, org)); err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, auditEntry{
+			OrganizationID: org.ID,
+			Action:         "organization.create",
+			ResourceType:   "organization",
+			ResourceID:     org.ID,
+			After:          org.auditFields(),
+		})
+	})
 	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, fmt.Errorf("organization %q %w", name, ErrConflict)
+		}
 		return nil, fmt.Errorf("creating organization: %w", err)
 	}
 	return org, nil
@@ -39,7 +65,7 @@ func (r *OrganizationRepository) Create(ctx context.Context, name string) (*Orga
 
 func (r *OrganizationRepository) GetByID(ctx context.Context, id string) (*Organization, error) {
 	org := &Organization{}
-	err := r.pool.QueryRow(ctx,
+	err := reader(r.pool, r.replicas).QueryRow(ctx,
 		`SELECT id, name, created_at, updated_at FROM organizations
 		 WHERE id = $1 AND deleted_at IS NULL`,
 		id,
@@ -70,7 +96,7 @@ func (r *OrganizationRepository) GetByName(ctx context.Context, name string) (*O
 }
 
 func (r *OrganizationRepository) List(ctx context.Context, limit, offset int) ([]*Organization, error) {
-	rows, err := r.pool.Query(ctx,
+	rows, err := reader(r.pool, r.replicas).Query(ctx,
 		`SELECT id, name, created_at, updated_at FROM organizations
 		 WHERE deleted_at IS NULL
 		 ORDER BY created_at DESC
@@ -94,32 +120,91 @@ func (r *OrganizationRepository) List(ctx context.Context, limit, offset int) ([
 }
 
 func (r *OrganizationRepository) Update(ctx context.Context, id, name string) (*Organization, error) {
+	if err := validateOrganizationName(name); err != nil {
+		return nil, err
+	}
 	org := &Organization{}
-	err := r.pool.QueryRow(ctx,
-		`UPDATE organizations SET name = $2, updated_at = now()
-		 WHERE id = $1 AND deleted_at IS NULL
-		 RETURNING id, name, created_at, updated_at`,
-		id, name,
-	).Scan(&org.ID, &org.Name, &org.CreatedAt, &org.UpdatedAt)
+	before := &Organization{}
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx,
+			`UPDATE organizations o SET name = $2, updated_at = now()
+			 FROM (SELECT id, name FROM organizations WHERE id = $1 AND deleted_at IS NULL FOR UPDATE) old
+			 WHERE o.id = old.id
+			 RETURNING o.id, o.name, o.created_at, o.updated_at, old.name`,
+			id, name,
+		).Scan(&org.ID, &org.Name, &org.CreatedAt, &org.UpdatedAt, &before.Name)
+		if err != nil {
+			return err
+		}
+		if err := appendEvent(ctx, tx, organizationEvent(EventOrganizationUpdated, org)); err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, auditEntry{
+			OrganizationID: org.ID,
+			Action:         "organization.update",
+			ResourceType:   "organization",
+			ResourceID:     org.ID,
+			Before:         before.auditFields(),
+			After:          org.auditFields(),
+		})
+	})
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return nil, nil
 		}
+		if isUniqueViolation(err) {
+			return nil, fmt.Errorf("organization %q %w", name, ErrConflict)
+		}
 		return nil, fmt.Errorf("updating organization: %w", err)
 	}
 	return org, nil
 }
 
 func (r *OrganizationRepository) Delete(ctx context.Context, id string) error {
-	result, err := r.pool.Exec(ctx,
-		`UPDATE organizations SET deleted_at = now() WHERE id = $1 AND deleted_at IS NULL`,
-		id,
-	)
+	org := &Organization{}
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx,
+			`UPDATE organizations SET deleted_at = now() WHERE id = $1 AND deleted_at IS NULL
+			 RETURNING id, name, created_at, updated_at`,
+			id,
+		).Scan(&org.ID, &org.Name, &org.CreatedAt, &org.UpdatedAt)
+		if err != nil {
+			return err
+		}
+		if err := appendEvent(ctx, tx, organizationEvent(EventOrganizationDeleted, org)); err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, auditEntry{
+			OrganizationID: org.ID,
+			Action:         "organization.delete",
+			ResourceType:   "organization",
+			ResourceID:     org.ID,
+			Before:         org.auditFields(),
+		})
+	})
 	if err != nil {
+		if err == pgx.ErrNoRows {
+			return fmt.Errorf("organization %w", ErrNotFound)
+		}
 		return fmt.Errorf("deleting organization: %w", err)
 	}
-	if result.RowsAffected() == 0 {
-		return fmt.Errorf("organization not found")
+	return nil
+}
+
+const maxOrganizationNameLength = 100
+
+func validateOrganizationName(name string) error {
+	if strings.TrimSpace(name) == "" || len(name) > maxOrganizationNameLength {
+		return fmt.Errorf("organization name %w: must be 1 to %d characters", ErrInvalid, maxOrganizationNameLength)
 	}
 	return nil
 }
+
+func (o *Organization) auditFields() map[string]any {
+	return map[string]any{"name": o.Name}
+}
+
+func organizationEvent(eventType string, org *Organization) *Event {
+	data, _ := json.Marshal(map[string]string{"id": org.ID, "name": org.Name})
+	return &Event{Type: eventType, OrganizationID: org.ID, Data: data}
+}
@@ -2,7 +2,9 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/flockiot/flock-api/database"
@@ -24,7 +26,7 @@ func testPool(t *testing.T) *database.TestDB {
 
 func TestOrganizationCreate(t *testing.T) {
 	db := testPool(t)
-	repo := NewOrganizationRepository(db.Pool)
+	repo := NewOrganizationRepository(db.Pool, nil)
 
 	org, err := repo.Create(context.Background(), "acmecorp")
 	if err != nil {
@@ -40,7 +42,7 @@ func TestOrganizationCreate(t *testing.T) {
 
 func TestOrganizationGetByID(t *testing.T) {
 	db := testPool(t)
-	repo := NewOrganizationRepository(db.Pool)
+	repo := NewOrganizationRepository(db.Pool, nil)
 
 	created, err := repo.Create(context.Background(), "getbyid-org")
 	if err != nil {
@@ -61,7 +63,7 @@ func TestOrganizationGetByID(t *testing.T) {
 
 func TestOrganizationGetByIDNotFound(t *testing.T) {
 	db := testPool(t)
-	repo := NewOrganizationRepository(db.Pool)
+	repo := NewOrganizationRepository(db.Pool, nil)
 
 	found, err := repo.GetByID(context.Background(), "00000000-0000-0000-0000-000000000000")
 	if err != nil {
@@ -74,7 +76,7 @@ func TestOrganizationGetByIDNotFound(t *testing.T) {
 
 func TestOrganizationGetByName(t *testing.T) {
 	db := testPool(t)
-	repo := NewOrganizationRepository(db.Pool)
+	repo := NewOrganizationRepository(db.Pool, nil)
 
 	_, err := repo.Create(context.Background(), "byname-org")
 	if err != nil {
@@ -95,7 +97,7 @@ func TestOrganizationGetByName(t *testing.T) {
 
 func TestOrganizationList(t *testing.T) {
 	db := testPool(t)
-	repo := NewOrganizationRepository(db.Pool)
+	repo := NewOrganizationRepository(db.Pool, nil)
 
 	for i := range 3 {
 		name := "list-org-" + string(rune('a'+i))
@@ -115,7 +117,7 @@ func TestOrganizationList(t *testing.T) {
 
 func TestOrganizationUpdate(t *testing.T) {
 	db := testPool(t)
-	repo := NewOrganizationRepository(db.Pool)
+	repo := NewOrganizationRepository(db.Pool, nil)
 
 	created, err := repo.Create(context.Background(), "oldname")
 	if err != nil {
@@ -136,7 +138,7 @@ func TestOrganizationUpdate(t *testing.T) {
 
 func TestOrganizationDelete(t *testing.T) {
 	db := testPool(t)
-	repo := NewOrganizationRepository(db.Pool)
+	repo := NewOrganizationRepository(db.Pool, nil)
 
 	created, err := repo.Create(context.Background(), "deleteme")
 	if err != nil {
@@ -158,10 +160,34 @@ func TestOrganizationDelete(t *testing.T) {
 
 func TestOrganizationDeleteNotFound(t *testing.T) {
 	db := testPool(t)
-	repo := NewOrganizationRepository(db.Pool)
+	repo := NewOrganizationRepository(db.Pool, nil)
 
 	err := repo.Delete(context.Background(), "00000000-0000-0000-0000-000000000000")
 	if err == nil {
 		t.Fatal("expected error deleting nonexistent organization")
 	}
 }
+
+func TestOrganizationCreateInvalidName(t *testing.T) {
+	repo := NewOrganizationRepository(nil, nil)
+
+	for _, name := range []string{"", "   ", strings.Repeat("a", 101)} {
+		_, err := repo.Create(context.Background(), name)
+		if !errors.Is(err, ErrInvalid) {
+			t.Errorf("Create(%q) error = %v, want ErrInvalid", name, err)
+		}
+	}
+}
+
+func TestOrganizationCreateDuplicate(t *testing.T) {
+	db := testPool(t)
+	repo := NewOrganizationRepository(db.Pool, nil)
+
+	if _, err := repo.Create(context.Background(), "duplicate-org"); err != nil {
+		t.Fatalf("failed to create: %v", err)
+	}
+	_, err := repo.Create(context.Background(), "duplicate-org")
+	if !errors.Is(err, ErrConflict) {
+		t.Fatalf("expected ErrConflict, got %v", err)
+	}
+}
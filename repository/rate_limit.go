@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type RateLimitRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewRateLimitRepository(pool *pgxpool.Pool) *RateLimitRepository {
+	return &RateLimitRepository{pool: pool}
+}
+
+// Take measures elapsed time with the database clock so replicas need not
+// agree.
+func (r *RateLimitRepository) Take(ctx context.Context, key string, rate float64, burst int) (remaining float64, allowed bool, err error) {
+	err = r.pool.QueryRow(ctx,
+		`WITH prev AS (
+		     SELECT tokens, updated_at FROM rate_limit_buckets WHERE key = $1 FOR UPDATE
+		 ), refilled AS (
+		     SELECT least($3::float8, coalesce(
+		         (SELECT tokens + greatest(0, extract(epoch FROM now() - updated_at))::float8 * $2::float8 FROM prev),
+		         $3::float8)) AS tokens
+		 )
+		 INSERT INTO rate_limit_buckets (key, tokens, updated_at)
+		 SELECT $1, CASE WHEN tokens >= 1 THEN tokens - 1 ELSE tokens END, now() FROM refilled
+		 ON CONFLICT (key) DO UPDATE SET tokens = EXCLUDED.tokens, updated_at = EXCLUDED.updated_at
+		 RETURNING tokens, (SELECT tokens >= 1 FROM refilled)`,
+		key, rate, burst,
+	).Scan(&remaining, &allowed)
+	if err != nil {
+		return 0, false, fmt.Errorf("taking rate limit token: %w", err)
+	}
+	return remaining, allowed, nil
+}
+
+// A deleted bucket is recreated full.
+func (r *RateLimitRepository) DeleteIdle(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM rate_limit_buckets WHERE updated_at < $1`, before)
+	if err != nil {
+		return 0, fmt.Errorf("deleting idle rate limit buckets: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,32 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRateLimitTake(t *testing.T) {
+	db := testPool(t)
+	repo := NewRateLimitRepository(db.Pool)
+	ctx := context.Background()
+	key := "test:" + time.Now().Format(time.RFC3339Nano)
+
+	for i, want := range []bool{true, true, false} {
+		_, allowed, err := repo.Take(ctx, key, 0.001, 2)
+		if err != nil {
+			t.Fatalf("take %d: %v", i, err)
+		}
+		if allowed != want {
+			t.Fatalf("take %d: allowed = %v, want %v", i, allowed, want)
+		}
+	}
+
+	n, err := repo.DeleteIdle(ctx, time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("failed to delete idle buckets: %v", err)
+	}
+	if n < 1 {
+		t.Fatalf("expected the bucket to be deleted, got %d", n)
+	}
+}
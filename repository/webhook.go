@@ -0,0 +1,509 @@
+package repository
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/flockiot/flock-api/database"
+)
+
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+type WebhookSubscription struct {
+	ID                  string
+	OrganizationID      string
+	URL                 string
+	Secret              string
+	EventTypes          []string
+	Enabled             bool
+	ConsecutiveFailures int
+	DisabledAt          *time.Time
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+}
+
+// Nil fields of a WebhookUpdate are left untouched.
+type WebhookUpdate struct {
+	URL        *string
+	EventTypes *[]string
+	Enabled    *bool
+}
+
+type WebhookDelivery struct {
+	ID             string
+	SubscriptionID string
+	EventID        int64
+	EventType      string
+	Payload        json.RawMessage
+	Status         string
+	Attempts       int
+	NextAttemptAt  time.Time
+	LastStatusCode *int
+	LastError      *string
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+type WebhookAttempt struct {
+	ID         int64
+	DeliveryID string
+	StatusCode *int
+	Error      *string
+	DurationMS int
+	CreatedAt  time.Time
+}
+
+type ClaimedDelivery struct {
+	WebhookDelivery
+	URL    string
+	Secret string
+}
+
+type AttemptOutcome struct {
+	StatusCode   int
+	Error        string
+	Duration     time.Duration
+	Succeeded    bool
+	RetryAt      *time.Time
+	DisableAfter int
+}
+
+type WebhookRepository struct {
+	pool     *pgxpool.Pool
+	replicas *database.Replicas
+}
+
+func NewWebhookRepository(pool *pgxpool.Pool, replicas *database.Replicas) *WebhookRepository {
+	return &WebhookRepository{pool: pool, replicas: replicas}
+}
+
+const webhookColumns = `id, organization_id, url, secret, event_types, enabled, consecutive_failures, disabled_at, created_at, updated_at`
+
+func scanWebhook(row pgx.Row) (*WebhookSubscription, error) {
+	w := &WebhookSubscription{}
+	err := row.Scan(&w.ID, &w.OrganizationID, &w.URL, &w.Secret, &w.EventTypes, &w.Enabled,
+		&w.ConsecutiveFailures, &w.DisabledAt, &w.CreatedAt, &w.UpdatedAt)
+	return w, err
+}
+
+func (r *WebhookRepository) Create(ctx context.Context, organizationID, url string, eventTypes []string) (*WebhookSubscription, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("generating webhook secret: %w", err)
+	}
+	if eventTypes == nil {
+		eventTypes = []string{}
+	}
+
+	var w *WebhookSubscription
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		var err error
+		w, err = scanWebhook(tx.QueryRow(ctx,
+			`INSERT INTO webhook_subscriptions (organization_id, url, secret, event_types)
+			 VALUES ($1, $2, $3, $4)
+			 RETURNING `+webhookColumns,
+			organizationID, url, "whsec_"+hex.EncodeToString(secret), eventTypes,
+		))
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, auditEntry{
+			OrganizationID: w.OrganizationID,
+			Action:         "webhook.create",
+			ResourceType:   "webhook",
+			ResourceID:     w.ID,
+			After:          w.auditFields(),
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating webhook: %w", err)
+	}
+	return w, nil
+}
+
+// Use GetByIDPrimary before acting on the result.
+func (r *WebhookRepository) GetByID(ctx context.Context, organizationID, id string) (*WebhookSubscription, error) {
+	return r.getByID(ctx, reader(r.pool, r.replicas), organizationID, id)
+}
+
+func (r *WebhookRepository) GetByIDPrimary(ctx context.Context, organizationID, id string) (*WebhookSubscription, error) {
+	return r.getByID(ctx, r.pool, organizationID, id)
+}
+
+func (r *WebhookRepository) getByID(ctx context.Context, q *pgxpool.Pool, organizationID, id string) (*WebhookSubscription, error) {
+	w, err := scanWebhook(q.QueryRow(ctx,
+		`SELECT `+webhookColumns+` FROM webhook_subscriptions
+		 WHERE organization_id = $1 AND id = $2 AND deleted_at IS NULL`,
+		organizationID, id,
+	))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("getting webhook by id: %w", err)
+	}
+	return w, nil
+}
+
+func (r *WebhookRepository) List(ctx context.Context, organizationID string, limit, offset int) ([]*WebhookSubscription, error) {
+	rows, err := reader(r.pool, r.replicas).Query(ctx,
+		`SELECT `+webhookColumns+` FROM webhook_subscriptions
+		 WHERE organization_id = $1 AND deleted_at IS NULL
+		 ORDER BY created_at DESC
+		 LIMIT $2 OFFSET $3`,
+		organizationID, limit, offset,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("listing webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	var hooks []*WebhookSubscription
+	for rows.Next() {
+		w, err := scanWebhook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning webhook: %w", err)
+		}
+		hooks = append(hooks, w)
+	}
+	return hooks, rows.Err()
+}
+
+// Re-enabling a subscription clears its failure count.
+func (r *WebhookRepository) Update(ctx context.Context, organizationID, id string, u WebhookUpdate) (*WebhookSubscription, error) {
+	var eventTypes []string
+	if u.EventTypes != nil {
+		eventTypes = *u.EventTypes
+		if eventTypes == nil {
+			eventTypes = []string{}
+		}
+	}
+
+	var w *WebhookSubscription
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		before, err := scanWebhook(tx.QueryRow(ctx,
+			`SELECT `+webhookColumns+` FROM webhook_subscriptions
+			 WHERE organization_id = $1 AND id = $2 AND deleted_at IS NULL
+			 FOR UPDATE`,
+			organizationID, id,
+		))
+		if err != nil {
+			return err
+		}
+
+		w, err = scanWebhook(tx.QueryRow(ctx,
+			`UPDATE webhook_subscriptions SET
+			     url = coalesce($2, url),
+			     event_types = coalesce($3, event_types),
+			     enabled = coalesce($4, enabled),
+			     consecutive_failures = CASE WHEN $4 THEN 0 ELSE consecutive_failures END,
+			     disabled_at = CASE WHEN $4 THEN NULL WHEN NOT $4 THEN coalesce(disabled_at, now()) ELSE disabled_at END,
+			     updated_at = now()
+			 WHERE id = $1
+			 RETURNING `+webhookColumns,
+			id, u.URL, eventTypes, u.Enabled,
+		))
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, auditEntry{
+			OrganizationID: w.OrganizationID,
+			Action:         "webhook.update",
+			ResourceType:   "webhook",
+			ResourceID:     w.ID,
+			Before:         before.auditFields(),
+			After:          w.auditFields(),
+		})
+	})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("updating webhook: %w", err)
+	}
+	return w, nil
+}
+
+func (r *WebhookRepository) Delete(ctx context.Context, organizationID, id string) error {
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		w, err := scanWebhook(tx.QueryRow(ctx,
+			`UPDATE webhook_subscriptions SET deleted_at = now(), enabled = false
+			 WHERE organization_id = $1 AND id = $2 AND deleted_at IS NULL
+			 RETURNING `+webhookColumns,
+			organizationID, id,
+		))
+		if err != nil {
+			return err
+		}
+		before := w.auditFields()
+		before["enabled"] = true
+		return recordAudit(ctx, tx, auditEntry{
+			OrganizationID: w.OrganizationID,
+			Action:         "webhook.delete",
+			ResourceType:   "webhook",
+			ResourceID:     w.ID,
+			Before:         before,
+		})
+	})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return fmt.Errorf("webhook %w", ErrNotFound)
+		}
+		return fmt.Errorf("deleting webhook: %w", err)
+	}
+	return nil
+}
+
+func (w *WebhookSubscription) auditFields() map[string]any {
+	return map[string]any{
+		"url":         w.URL,
+		"event_types": w.EventTypes,
+		"enabled":     w.Enabled,
+	}
+}
+
+const deliveryColumns = `id, subscription_id, event_id, event_type, payload, status, attempts, next_attempt_at, last_status_code, last_error, created_at, updated_at`
+
+func scanDelivery(row pgx.Row, extra ...any) (*WebhookDelivery, error) {
+	d := &WebhookDelivery{}
+	dest := append([]any{&d.ID, &d.SubscriptionID, &d.EventID, &d.EventType, &d.Payload, &d.Status,
+		&d.Attempts, &d.NextAttemptAt, &d.LastStatusCode, &d.LastError, &d.CreatedAt, &d.UpdatedAt}, extra...)
+	err := row.Scan(dest...)
+	return d, err
+}
+
+func (r *WebhookRepository) ListDeliveries(ctx context.Context, subscriptionID string, limit, offset int) ([]*WebhookDelivery, error) {
+	rows, err := r.pool.Query(ctx,
+		`SELECT `+deliveryColumns+` FROM webhook_deliveries
+		 WHERE subscription_id = $1
+		 ORDER BY created_at DESC
+		 LIMIT $2 OFFSET $3`,
+		subscriptionID, limit, offset,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("listing webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []*WebhookDelivery
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+func (r *WebhookRepository) GetDelivery(ctx context.Context, subscriptionID, id string) (*WebhookDelivery, error) {
+	d, err := scanDelivery(r.pool.QueryRow(ctx,
+		`SELECT `+deliveryColumns+` FROM webhook_deliveries
+		 WHERE subscription_id = $1 AND id = $2`,
+		subscriptionID, id,
+	))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("getting webhook delivery: %w", err)
+	}
+	return d, nil
+}
+
+func (r *WebhookRepository) ListAttempts(ctx context.Context, deliveryID string) ([]*WebhookAttempt, error) {
+	rows, err := r.pool.Query(ctx,
+		`SELECT id, delivery_id, status_code, error, duration_ms, created_at
+		 FROM webhook_delivery_attempts
+		 WHERE delivery_id = $1
+		 ORDER BY id`,
+		deliveryID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("listing webhook attempts: %w", err)
+	}
+	defer rows.Close()
+
+	var attempts []*WebhookAttempt
+	for rows.Next() {
+		a := &WebhookAttempt{}
+		if err := rows.Scan(&a.ID, &a.DeliveryID, &a.StatusCode, &a.Error, &a.DurationMS, &a.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scanning webhook attempt: %w", err)
+		}
+		attempts = append(attempts, a)
+	}
+	return attempts, rows.Err()
+}
+
+// Redeliver resets the attempt count, so the delivery gets the full number of
+// retries; earlier attempts stay in its history.
+func (r *WebhookRepository) Redeliver(ctx context.Context, subscriptionID, id string) (*WebhookDelivery, error) {
+	var d *WebhookDelivery
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		var organizationID, previousStatus string
+		var previousAttempts int
+		var err error
+		d, err = scanDelivery(tx.QueryRow(ctx,
+			`UPDATE webhook_deliveries d SET status = 'pending', attempts = 0, next_attempt_at = now(), updated_at = now()
+			 FROM webhook_subscriptions s, (SELECT id, status, attempts FROM webhook_deliveries WHERE id = $2 FOR UPDATE) old
+			 WHERE s.id = d.subscription_id AND old.id = d.id AND d.subscription_id = $1
+			 RETURNING d.id, d.subscription_id, d.event_id, d.event_type, d.payload, d.status, d.attempts,
+			           d.next_attempt_at, d.last_status_code, d.last_error, d.created_at, d.updated_at,
+			           s.organization_id, old.status, old.attempts`,
+			subscriptionID, id,
+		), &organizationID, &previousStatus, &previousAttempts)
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, auditEntry{
+			OrganizationID: organizationID,
+			Action:         "webhook.redeliver",
+			ResourceType:   "webhook_delivery",
+			ResourceID:     d.ID,
+			Before:         map[string]any{"status": previousStatus, "attempts": previousAttempts},
+			After:          map[string]any{"status": d.Status, "attempts": d.Attempts},
+		})
+	})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("redelivering webhook: %w", err)
+	}
+	return d, nil
+}
+
+// ClaimDue leases deliveries by pushing their next attempt out by lease.
+// Rows locked by another worker are skipped, so dispatchers can share the
+// queue.
+func (r *WebhookRepository) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]*ClaimedDelivery, error) {
+	rows, err := r.pool.Query(ctx,
+		`WITH claimed AS (
+		     UPDATE webhook_deliveries SET next_attempt_at = now() + $2::interval
+		     WHERE id IN (
+		         SELECT d.id FROM webhook_deliveries d
+		         JOIN webhook_subscriptions s ON s.id = d.subscription_id
+		         WHERE d.status = 'pending' AND d.next_attempt_at <= now()
+		           AND s.enabled AND s.deleted_at IS NULL
+		         ORDER BY d.next_attempt_at
+		         LIMIT $1
+		         FOR UPDATE OF d SKIP LOCKED
+		     )
+		     RETURNING `+deliveryColumns+`
+		 )
+		 SELECT c.id, c.subscription_id, c.event_id, c.event_type, c.payload, c.status, c.attempts,
+		        c.next_attempt_at, c.last_status_code, c.last_error, c.created_at, c.updated_at, s.url, s.secret
+		 FROM claimed c JOIN webhook_subscriptions s ON s.id = c.subscription_id`,
+		limit, lease,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("claiming webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var claimed []*ClaimedDelivery
+	for rows.Next() {
+		c := &ClaimedDelivery{}
+		d, err := scanDelivery(rows, &c.URL, &c.Secret)
+		if err != nil {
+			return nil, fmt.Errorf("scanning claimed delivery: %w", err)
+		}
+		c.WebhookDelivery = *d
+		claimed = append(claimed, c)
+	}
+	return claimed, rows.Err()
+}
+
+// RecordAttempt disables the subscription once its consecutive failures reach
+// o.DisableAfter.
+func (r *WebhookRepository) RecordAttempt(ctx context.Context, d *ClaimedDelivery, o AttemptOutcome) error {
+	var statusCode *int
+	if o.StatusCode != 0 {
+		statusCode = &o.StatusCode
+	}
+	var errMsg *string
+	if o.Error != "" {
+		errMsg = &o.Error
+	}
+
+	status := DeliveryPending
+	nextAttempt := time.Now()
+	switch {
+	case o.Succeeded:
+		status = DeliverySucceeded
+	case o.RetryAt == nil:
+		status = DeliveryFailed
+	default:
+		nextAttempt = *o.RetryAt
+	}
+
+	return pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx,
+			`INSERT INTO webhook_delivery_attempts (delivery_id, status_code, error, duration_ms)
+			 VALUES ($1, $2, $3, $4)`,
+			d.ID, statusCode, errMsg, o.Duration.Milliseconds(),
+		); err != nil {
+			return fmt.Errorf("recording webhook attempt: %w", err)
+		}
+
+		if _, err := tx.Exec(ctx,
+			`UPDATE webhook_deliveries SET
+			     status = $2, attempts = attempts + 1, next_attempt_at = $3,
+			     last_status_code = $4, last_error = $5, updated_at = now()
+			 WHERE id = $1`,
+			d.ID, status, nextAttempt, statusCode, errMsg,
+		); err != nil {
+			return fmt.Errorf("updating webhook delivery: %w", err)
+		}
+
+		if o.Succeeded {
+			_, err := tx.Exec(ctx,
+				`UPDATE webhook_subscriptions SET consecutive_failures = 0 WHERE id = $1`,
+				d.SubscriptionID,
+			)
+			if err != nil {
+				return fmt.Errorf("resetting webhook failures: %w", err)
+			}
+			return nil
+		}
+
+		var organizationID string
+		var disabled bool
+		err := tx.QueryRow(ctx,
+			`UPDATE webhook_subscriptions s SET
+			     consecutive_failures = s.consecutive_failures + 1,
+			     enabled = s.enabled AND s.consecutive_failures + 1 < $2,
+			     disabled_at = CASE WHEN s.enabled AND s.consecutive_failures + 1 >= $2 THEN now() ELSE s.disabled_at END,
+			     updated_at = now()
+			 FROM (SELECT id, enabled FROM webhook_subscriptions WHERE id = $1 FOR UPDATE) old
+			 WHERE s.id = old.id
+			 RETURNING s.organization_id, old.enabled AND NOT s.enabled`,
+			d.SubscriptionID, o.DisableAfter,
+		).Scan(&organizationID, &disabled)
+		if err != nil {
+			return fmt.Errorf("counting webhook failure: %w", err)
+		}
+		if !disabled {
+			return nil
+		}
+		return recordAudit(ctx, tx, auditEntry{
+			OrganizationID: organizationID,
+			Action:         "webhook.disable",
+			ResourceType:   "webhook",
+			ResourceID:     d.SubscriptionID,
+			Before:         map[string]any{"enabled": true},
+			After:          map[string]any{"enabled": false},
+		})
+	})
+}
@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWebhookCreateAndList(t *testing.T) {
+	db := testPool(t)
+	orgs := NewOrganizationRepository(db.Pool, nil)
+	hooks := NewWebhookRepository(db.Pool, nil)
+
+	org, err := orgs.Create(context.Background(), "webhook-list-org")
+	if err != nil {
+		t.Fatalf("failed to create organization: %v", err)
+	}
+	hook, err := hooks.Create(context.Background(), org.ID, "https://example.com/hook", nil)
+	if err != nil {
+		t.Fatalf("failed to create webhook: %v", err)
+	}
+	if hook.Secret == "" || !hook.Enabled {
+		t.Fatalf("expected enabled webhook with secret, got %+v", hook)
+	}
+
+	list, err := hooks.List(context.Background(), org.ID, 10, 0)
+	if err != nil {
+		t.Fatalf("failed to list: %v", err)
+	}
+	if len(list) != 1 || list[0].ID != hook.ID {
+		t.Fatalf("expected webhook %q in list, got %d entries", hook.ID, len(list))
+	}
+}
+
+func TestWebhookEventEnqueuesDelivery(t *testing.T) {
+	db := testPool(t)
+	orgs := NewOrganizationRepository(db.Pool, nil)
+	hooks := NewWebhookRepository(db.Pool, nil)
+
+	org, err := orgs.Create(context.Background(), "webhook-enqueue-org")
+	if err != nil {
+		t.Fatalf("failed to create organization: %v", err)
+	}
+	hook, err := hooks.Create(context.Background(), org.ID, "https://example.com/hook", []string{EventOrganizationUpdated})
+	if err != nil {
+		t.Fatalf("failed to create webhook: %v", err)
+	}
+	if _, err := orgs.Update(context.Background(), org.ID, "webhook-enqueue-org-2"); err != nil {
+		t.Fatalf("failed to update organization: %v", err)
+	}
+
+	deliveries, err := hooks.ListDeliveries(context.Background(), hook.ID, 10, 0)
+	if err != nil {
+		t.Fatalf("failed to list deliveries: %v", err)
+	}
+	if len(deliveries) != 1 || deliveries[0].EventType != EventOrganizationUpdated {
+		t.Fatalf("expected one organization.updated delivery, got %d", len(deliveries))
+	}
+	if deliveries[0].Status != DeliveryPending {
+		t.Fatalf("expected pending delivery, got %q", deliveries[0].Status)
+	}
+}
+
+func TestWebhookDisabledAfterFailures(t *testing.T) {
+	db := testPool(t)
+	orgs := NewOrganizationRepository(db.Pool, nil)
+	hooks := NewWebhookRepository(db.Pool, nil)
+
+	org, err := orgs.Create(context.Background(), "webhook-disable-org")
+	if err != nil {
+		t.Fatalf("failed to create organization: %v", err)
+	}
+	hook, err := hooks.Create(context.Background(), org.ID, "https://example.com/hook", nil)
+	if err != nil {
+		t.Fatalf("failed to create webhook: %v", err)
+	}
+	if _, err := orgs.Update(context.Background(), org.ID, "webhook-disable-org-2"); err != nil {
+		t.Fatalf("failed to update organization: %v", err)
+	}
+
+	deliveries, err := hooks.ListDeliveries(context.Background(), hook.ID, 1, 0)
+	if err != nil || len(deliveries) == 0 {
+		t.Fatalf("expected a delivery, got %v", err)
+	}
+	claimed := &ClaimedDelivery{WebhookDelivery: *deliveries[0]}
+	retryAt := time.Now().Add(time.Minute)
+	for range 2 {
+		err := hooks.RecordAttempt(context.Background(), claimed, AttemptOutcome{
+			StatusCode:   500,
+			Error:        "unexpected status 500",
+			RetryAt:      &retryAt,
+			DisableAfter: 2,
+		})
+		if err != nil {
+			t.Fatalf("failed to record attempt: %v", err)
+		}
+	}
+
+	got, err := hooks.GetByIDPrimary(context.Background(), org.ID, hook.ID)
+	if err != nil {
+		t.Fatalf("failed to get webhook: %v", err)
+	}
+	if got.Enabled || got.DisabledAt == nil || got.ConsecutiveFailures != 2 {
+		t.Fatalf("expected webhook disabled after 2 failures, got %+v", got)
+	}
+
+	attempts, err := hooks.ListAttempts(context.Background(), claimed.ID)
+	if err != nil {
+		t.Fatalf("failed to list attempts: %v", err)
+	}
+	if len(attempts) != 2 {
+		t.Fatalf("expected 2 attempts, got %d", len(attempts))
+	}
+}
+
+func TestWebhookRedeliverResetsAttempts(t *testing.T) {
+	db := testPool(t)
+	orgs := NewOrganizationRepository(db.Pool, nil)
+	hooks := NewWebhookRepository(db.Pool, nil)
+
+	org, err := orgs.Create(context.Background(), "webhook-redeliver-org")
+	if err != nil {
+		t.Fatalf("failed to create organization: %v", err)
+	}
+	hook, err := hooks.Create(context.Background(), org.ID, "https://example.com/hook", nil)
+	if err != nil {
+		t.Fatalf("failed to create webhook: %v", err)
+	}
+	if _, err := orgs.Update(context.Background(), org.ID, "webhook-redeliver-org-2"); err != nil {
+		t.Fatalf("failed to update organization: %v", err)
+	}
+	deliveries, err := hooks.ListDeliveries(context.Background(), hook.ID, 1, 0)
+	if err != nil || len(deliveries) == 0 {
+		t.Fatalf("expected a delivery, got %v", err)
+	}
+	claimed := &ClaimedDelivery{WebhookDelivery: *deliveries[0]}
+	if err := hooks.RecordAttempt(context.Background(), claimed, AttemptOutcome{StatusCode: 500, Error: "unexpected status 500"}); err != nil {
+		t.Fatalf("failed to record attempt: %v", err)
+	}
+
+	d, err := hooks.Redeliver(context.Background(), hook.ID, claimed.ID)
+	if err != nil {
+		t.Fatalf("failed to redeliver: %v", err)
+	}
+	if d.Status != DeliveryPending || d.Attempts != 0 {
+		t.Fatalf("expected a pending delivery with no attempts, got %+v", d)
+	}
+	attempts, err := hooks.ListAttempts(context.Background(), claimed.ID)
+	if err != nil {
+		t.Fatalf("failed to list attempts: %v", err)
+	}
+	if len(attempts) != 1 {
+		t.Fatalf("expected the earlier attempt to be kept, got %d", len(attempts))
+	}
+}
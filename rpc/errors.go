@@ -0,0 +1,36 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/flockiot/flock-api/repository"
+)
+
+var (
+	errForbidden = status.Error(codes.PermissionDenied, "forbidden")
+	errInternal  = status.Error(codes.Internal, "internal error")
+)
+
+// repositoryError matches the api package's writeRepositoryError.
+func repositoryError(ctx context.Context, err error, op string) error {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, repository.ErrConflict):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, repository.ErrInvalid):
+		return status.Error(codes.InvalidArgument, err.Error())
+	default:
+		slog.ErrorContext(ctx, op+" failed", "error", err)
+		return errInternal
+	}
+}
+
+func notFound(msg string) error {
+	return status.Error(codes.NotFound, msg)
+}
@@ -0,0 +1,130 @@
+package rpc
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/flockiot/flock-api/auth"
+	flockv1 "github.com/flockiot/flock-api/proto/flock/v1"
+	"github.com/flockiot/flock-api/repository"
+)
+
+// Page size limits match the HTTP API.
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+)
+
+type organizationServer struct {
+	flockv1.UnimplementedOrganizationServiceServer
+	repo *repository.OrganizationRepository
+}
+
+func requireAdmin(ctx context.Context) error {
+	p, ok := auth.FromContext(ctx)
+	if !ok || !p.Admin {
+		return errForbidden
+	}
+	return nil
+}
+
+func requireOrgAccess(ctx context.Context, orgID string) error {
+	if !repository.IsUUID(orgID) {
+		return notFound("organization not found")
+	}
+	p, ok := auth.FromContext(ctx)
+	if !ok || !p.CanAccess(orgID) {
+		return errForbidden
+	}
+	return nil
+}
+
+func toOrganization(o *repository.Organization) *flockv1.Organization {
+	return &flockv1.Organization{
+		Id:         o.ID,
+		Name:       o.Name,
+		CreateTime: timestamppb.New(o.CreatedAt),
+		UpdateTime: timestamppb.New(o.UpdatedAt),
+	}
+}
+
+func (s *organizationServer) CreateOrganization(ctx context.Context, req *flockv1.CreateOrganizationRequest) (*flockv1.CreateOrganizationResponse, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	org, err := s.repo.Create(ctx, req.GetName())
+	if err != nil {
+		return nil, repositoryError(ctx, err, "creating organization")
+	}
+	return &flockv1.CreateOrganizationResponse{Organization: toOrganization(org)}, nil
+}
+
+func (s *organizationServer) GetOrganization(ctx context.Context, req *flockv1.GetOrganizationRequest) (*flockv1.GetOrganizationResponse, error) {
+	if err := requireOrgAccess(ctx, req.GetId()); err != nil {
+		return nil, err
+	}
+	org, err := s.repo.GetByID(ctx, req.GetId())
+	if err != nil {
+		return nil, repositoryError(ctx, err, "getting organization")
+	}
+	if org == nil {
+		return nil, notFound("organization not found")
+	}
+	return &flockv1.GetOrganizationResponse{Organization: toOrganization(org)}, nil
+}
+
+func (s *organizationServer) ListOrganizations(ctx context.Context, req *flockv1.ListOrganizationsRequest) (*flockv1.ListOrganizationsResponse, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	limit := int(req.GetLimit())
+	if limit == 0 {
+		limit = defaultPageSize
+	}
+	if limit < 1 || limit > maxPageSize {
+		return nil, status.Errorf(codes.InvalidArgument, "limit must be between 1 and %d", maxPageSize)
+	}
+	if req.GetOffset() < 0 {
+		return nil, status.Error(codes.InvalidArgument, "offset must be a non-negative integer")
+	}
+
+	orgs, err := s.repo.List(ctx, limit, int(req.GetOffset()))
+	if err != nil {
+		return nil, repositoryError(ctx, err, "listing organizations")
+	}
+	resp := &flockv1.ListOrganizationsResponse{}
+	for _, o := range orgs {
+		resp.Organizations = append(resp.Organizations, toOrganization(o))
+	}
+	return resp, nil
+}
+
+func (s *organizationServer) UpdateOrganization(ctx context.Context, req *flockv1.UpdateOrganizationRequest) (*flockv1.UpdateOrganizationResponse, error) {
+	if err := requireOrgAccess(ctx, req.GetId()); err != nil {
+		return nil, err
+	}
+	org, err := s.repo.Update(ctx, req.GetId(), req.GetName())
+	if err != nil {
+		return nil, repositoryError(ctx, err, "updating organization")
+	}
+	if org == nil {
+		return nil, notFound("organization not found")
+	}
+	return &flockv1.UpdateOrganizationResponse{Organization: toOrganization(org)}, nil
+}
+
+func (s *organizationServer) DeleteOrganization(ctx context.Context, req *flockv1.DeleteOrganizationRequest) (*flockv1.DeleteOrganizationResponse, error) {
+	if err := requireOrgAccess(ctx, req.GetId()); err != nil {
+		return nil, err
+	}
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Delete(ctx, req.GetId()); err != nil {
+		return nil, repositoryError(ctx, err, "deleting organization")
+	}
+	return &flockv1.DeleteOrganizationResponse{}, nil
+}
@@ -0,0 +1,152 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+
+	"github.com/flockiot/flock-api/auth"
+	"github.com/flockiot/flock-api/certs"
+	"github.com/flockiot/flock-api/config"
+	"github.com/flockiot/flock-api/database"
+	flockhealth "github.com/flockiot/flock-api/health"
+	flockv1 "github.com/flockiot/flock-api/proto/flock/v1"
+	"github.com/flockiot/flock-api/ratelimit"
+	"github.com/flockiot/flock-api/repository"
+)
+
+const healthCheckInterval = 5 * time.Second
+
+type Server struct {
+	grpc   *grpc.Server
+	health *health.Server
+	checks *flockhealth.Registry
+}
+
+// A nil limiter disables rate limiting.
+func NewServer(cfg *config.Config, pool *pgxpool.Pool, replicas *database.Replicas, limiter *ratelimit.Limiter, checks *flockhealth.Registry, opts ...grpc.ServerOption) *Server {
+	authenticator := auth.NewAuthenticator(cfg.Auth.AdminToken, nil)
+	if pool != nil {
+		authenticator = auth.NewAuthenticator(cfg.Auth.AdminToken, repository.NewAPIKeyRepository(pool))
+	}
+
+	unary := []grpc.UnaryServerInterceptor{
+		recoverUnary,
+		requestIDUnary,
+		clientIdentityUnary,
+		traceUnary,
+		logUnary,
+		authUnary(authenticator),
+	}
+	stream := []grpc.StreamServerInterceptor{
+		recoverStream,
+		requestIDStream,
+		clientIdentityStream,
+		traceStream,
+		logStream,
+		authStream(authenticator),
+	}
+	if limiter != nil {
+		unary = append(unary, rateLimitUnary(limiter))
+		stream = append(stream, rateLimitStream(limiter))
+	}
+
+	srv := grpc.NewServer(append([]grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(unary...),
+		grpc.ChainStreamInterceptor(stream...),
+	}, opts...)...)
+
+	flockv1.RegisterOrganizationServiceServer(srv, &organizationServer{
+		repo: repository.NewOrganizationRepository(pool, replicas),
+	})
+
+	hs := health.NewServer()
+	healthpb.RegisterHealthServer(srv, hs)
+	reflection.Register(srv)
+
+	return &Server{grpc: srv, health: hs, checks: checks}
+}
+
+func Start(ctx context.Context, cfg *config.Config, pool *pgxpool.Pool, replicas *database.Replicas, limiter *ratelimit.Limiter, checks *flockhealth.Registry) error {
+	tlsFiles, err := certs.New(cfg.Server)
+	if err != nil {
+		return err
+	}
+	var opts []grpc.ServerOption
+	if tlsFiles != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsFiles.TLSConfig("h2"))))
+	}
+	s := NewServer(cfg, pool, replicas, limiter, checks, opts...)
+
+	addr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.GRPCPort)
+	var listener flockhealth.Listener
+	checks.Register("api/grpc", listener.Check)
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	listener.Serving()
+	defer listener.Stopped()
+
+	go s.watchReadiness(ctx)
+
+	slog.Info("grpc server listening", "addr", lis.Addr().String(), "tls", tlsFiles != nil)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- s.grpc.Serve(lis)
+	}()
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	slog.Info("grpc server shutting down")
+	s.health.Shutdown()
+	stopped := make(chan struct{})
+	go func() {
+		s.grpc.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(cfg.Supervisor.ServerShutdownTimeout()):
+		slog.Error("grpc server shutdown timed out, closing connections")
+		s.grpc.Stop()
+	}
+	return nil
+}
+
+// watchReadiness mirrors /readyz into the gRPC health service.
+func (s *Server) watchReadiness(ctx context.Context) {
+	ticker := time.NewTicker(healthCheckInterval)
+	defer ticker.Stop()
+	for {
+		s.checkReadiness(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Server) checkReadiness(ctx context.Context) {
+	status := healthpb.HealthCheckResponse_SERVING
+	if _, ok := s.checks.Run(ctx); !ok {
+		status = healthpb.HealthCheckResponse_NOT_SERVING
+	}
+	s.health.SetServingStatus("", status)
+	for name := range s.grpc.GetServiceInfo() {
+		s.health.SetServingStatus(name, status)
+	}
+}
@@ -0,0 +1,178 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/flockiot/flock-api/auth"
+	"github.com/flockiot/flock-api/config"
+	"github.com/flockiot/flock-api/health"
+	flockv1 "github.com/flockiot/flock-api/proto/flock/v1"
+	"github.com/flockiot/flock-api/ratelimit"
+	"github.com/flockiot/flock-api/repository"
+)
+
+func testServer(t *testing.T) (*Server, *grpc.ClientConn) {
+	t.Helper()
+	checks := health.NewRegistry()
+	checks.Register("database", health.Database(nil))
+	s := NewServer(&config.Config{Auth: config.AuthConfig{AdminToken: "admin"}}, nil, nil, nil, checks)
+
+	lis := bufconn.Listen(1 << 20)
+	go func() { _ = s.grpc.Serve(lis) }()
+	t.Cleanup(s.grpc.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return s, conn
+}
+
+func withToken(token string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+}
+
+func TestHealthReflectsReadiness(t *testing.T) {
+	s, conn := testServer(t)
+	s.checkReadiness(context.Background())
+
+	resp, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatalf("health check without credentials failed: %v", err)
+	}
+	if resp.GetStatus() != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Fatalf("expected NOT_SERVING without a database, got %v", resp.GetStatus())
+	}
+
+	resp, err = healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{
+		Service: flockv1.OrganizationService_ServiceDesc.ServiceName,
+	})
+	if err != nil {
+		t.Fatalf("service health check failed: %v", err)
+	}
+	if resp.GetStatus() != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Fatalf("expected NOT_SERVING for organization service, got %v", resp.GetStatus())
+	}
+}
+
+func TestOrganizationRequiresAuth(t *testing.T) {
+	_, conn := testServer(t)
+	client := flockv1.NewOrganizationServiceClient(conn)
+
+	_, err := client.GetOrganization(context.Background(), &flockv1.GetOrganizationRequest{Id: "x"})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected Unauthenticated, got %v", err)
+	}
+
+	_, err = client.GetOrganization(withToken("wrong"), &flockv1.GetOrganizationRequest{Id: "x"})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected Unauthenticated for bad token, got %v", err)
+	}
+}
+
+func TestOrganizationInvalidID(t *testing.T) {
+	_, conn := testServer(t)
+	client := flockv1.NewOrganizationServiceClient(conn)
+
+	_, err := client.GetOrganization(withToken("admin"), &flockv1.GetOrganizationRequest{Id: "not-a-uuid"})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("expected NotFound, got %v", err)
+	}
+}
+
+func TestCreateOrganizationValidatesName(t *testing.T) {
+	_, conn := testServer(t)
+	client := flockv1.NewOrganizationServiceClient(conn)
+
+	_, err := client.CreateOrganization(withToken("admin"), &flockv1.CreateOrganizationRequest{Name: " "})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument, got %v", err)
+	}
+}
+
+func TestListOrganizationsValidatesLimit(t *testing.T) {
+	_, conn := testServer(t)
+	client := flockv1.NewOrganizationServiceClient(conn)
+
+	_, err := client.ListOrganizations(withToken("admin"), &flockv1.ListOrganizationsRequest{Limit: 1000})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument, got %v", err)
+	}
+}
+
+func TestRepositoryErrorMapping(t *testing.T) {
+	cases := []struct {
+		err  error
+		want codes.Code
+	}{
+		{fmt.Errorf("organization %w", repository.ErrNotFound), codes.NotFound},
+		{fmt.Errorf("organization %w", repository.ErrConflict), codes.AlreadyExists},
+		{fmt.Errorf("name %w", repository.ErrInvalid), codes.InvalidArgument},
+		{fmt.Errorf("boom"), codes.Internal},
+	}
+	for _, tc := range cases {
+		if got := status.Code(repositoryError(context.Background(), tc.err, "test")); got != tc.want {
+			t.Errorf("repositoryError(%v) = %v, want %v", tc.err, got, tc.want)
+		}
+	}
+}
+
+func TestRequestIDEchoed(t *testing.T) {
+	_, conn := testServer(t)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-request-id", "client-id-1")
+
+	var header metadata.MD
+	_, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{}, grpc.Header(&header))
+	if err != nil {
+		t.Fatalf("health check failed: %v", err)
+	}
+	if got := header.Get("x-request-id"); len(got) != 1 || got[0] != "client-id-1" {
+		t.Fatalf("expected request ID echoed, got %v", got)
+	}
+}
+
+func TestRateLimitUnary(t *testing.T) {
+	limiter, err := ratelimit.New(config.RateLimitConfig{
+		KeyBy:       "key",
+		DefaultTier: "default",
+		Tiers:       map[string]string{"default": "60/1m:1"},
+	}, ratelimit.NewMemoryStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+	intercept := rateLimitUnary(limiter)
+	handler := func(context.Context, any) (any, error) { return "ok", nil }
+	info := &grpc.UnaryServerInfo{FullMethod: "/flock.v1.OrganizationService/GetOrganization"}
+
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{KeyID: "key-1"})
+	if _, err := intercept(ctx, nil, info, handler); err != nil {
+		t.Fatalf("first call should be allowed: %v", err)
+	}
+	if _, err := intercept(ctx, nil, info, handler); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("expected ResourceExhausted once the bucket is empty, got %v", err)
+	}
+
+	admin := auth.WithPrincipal(context.Background(), &auth.Principal{Admin: true})
+	for range 3 {
+		if _, err := intercept(admin, nil, info, handler); err != nil {
+			t.Fatalf("admin should not be limited: %v", err)
+		}
+	}
+}
@@ -9,67 +9,149 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"github.com/flockiot/flock-api/config"
+	"github.com/flockiot/flock-api/database"
+	"github.com/flockiot/flock-api/health"
 )
 
 type Deps struct {
 	Config *config.Config
 	DB     *pgxpool.Pool
+	// Replicas is nil when none are configured.
+	Replicas *database.Replicas
+	Health   *health.Registry
 }
 
 type StartFunc func(ctx context.Context, deps *Deps) error
 
+type Spec struct {
+	Name      string
+	Start     StartFunc
+	Restart   RestartPolicy
+	Critical  bool
+	DependsOn []string
+}
+
+type Option func(*Spec)
+
+// The default is RestartNever.
+func WithRestart(p RestartPolicy) Option {
+	return func(s *Spec) { s.Restart = p }
+}
+
+func Critical() Option {
+	return func(s *Spec) { s.Critical = true }
+}
+
+// A dependency that is not selected is assumed to run in another process.
+func DependsOn(names ...string) Option {
+	return func(s *Spec) { s.DependsOn = append(s.DependsOn, names...) }
+}
+
 type Registry struct {
-	targets map[string]StartFunc
+	targets map[string]*Spec
 	order   []string
 }
 
 func New() *Registry {
 	return &Registry{
-		targets: make(map[string]StartFunc),
+		targets: make(map[string]*Spec),
 	}
 }
 
-func (r *Registry) Register(name string, fn StartFunc) {
+func (r *Registry) Register(name string, fn StartFunc, opts ...Option) {
 	if _, exists := r.targets[name]; exists {
 		panic(fmt.Sprintf("target %q already registered", name))
 	}
-	r.targets[name] = fn
+	spec := &Spec{Name: name, Start: fn, Restart: RestartNever}
+	for _, opt := range opts {
+		opt(spec)
+	}
+	if path := r.cycle(name, spec.DependsOn, nil); path != nil {
+		panic(fmt.Sprintf("target %q has a dependency cycle: %s", name, strings.Join(path, " -> ")))
+	}
+	r.targets[name] = spec
 	r.order = append(r.order, name)
 }
 
+// Dependencies that are not registered yet cannot close a cycle.
+func (r *Registry) cycle(name string, deps, path []string) []string {
+	for _, dep := range deps {
+		p := append(slices.Clone(path), dep)
+		if dep == name {
+			return append([]string{name}, p...)
+		}
+		if spec, ok := r.targets[dep]; ok {
+			if found := r.cycle(name, spec.DependsOn, p); found != nil {
+				return found
+			}
+		}
+	}
+	return nil
+}
+
 func (r *Registry) Get(name string) (StartFunc, bool) {
-	fn, ok := r.targets[name]
-	return fn, ok
+	spec, ok := r.targets[name]
+	if !ok {
+		return nil, false
+	}
+	return spec.Start, true
 }
 
 func (r *Registry) Names() []string {
 	return slices.Clone(r.order)
 }
 
-func (r *Registry) Resolve(raw string) (map[string]StartFunc, error) {
+// Resolve orders every target after its dependencies and otherwise in
+// registration order.
+func (r *Registry) Resolve(raw string) ([]Spec, error) {
+	selected := make(map[string]bool)
 	if raw == "all" {
-		result := make(map[string]StartFunc, len(r.targets))
-		for name, fn := range r.targets {
-			result[name] = fn
+		for _, name := range r.order {
+			selected[name] = true
+		}
+	} else {
+		for _, name := range strings.Split(raw, ",") {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+			if _, ok := r.targets[name]; !ok {
+				return nil, fmt.Errorf("unknown target %q (valid targets: %s)", name, strings.Join(r.order, ", "))
+			}
+			selected[name] = true
+		}
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("no targets specified (valid targets: %s)", strings.Join(r.order, ", "))
+	}
+
+	for _, name := range r.order {
+		for _, dep := range r.targets[name].DependsOn {
+			if _, ok := r.targets[dep]; !ok {
+				return nil, fmt.Errorf("target %q depends on unknown target %q", name, dep)
+			}
 		}
-		return result, nil
 	}
 
-	names := strings.Split(raw, ",")
-	result := make(map[string]StartFunc, len(names))
-	for _, name := range names {
-		name = strings.TrimSpace(name)
-		if name == "" {
-			continue
+	result := make([]Spec, 0, len(selected))
+	placed := make(map[string]bool, len(selected))
+	var place func(name string)
+	place = func(name string) {
+		if placed[name] {
+			return
 		}
-		fn, ok := r.targets[name]
-		if !ok {
-			return nil, fmt.Errorf("unknown target %q (valid targets: %s)", name, strings.Join(r.order, ", "))
+		placed[name] = true
+		for _, dep := range r.targets[name].DependsOn {
+			if selected[dep] {
+				place(dep)
+			}
 		}
-		result[name] = fn
+		result = append(result, *r.targets[name])
 	}
-	if len(result) == 0 {
-		return nil, fmt.Errorf("no targets specified (valid targets: %s)", strings.Join(r.order, ", "))
+	for _, name := range r.order {
+		if selected[name] {
+			place(name)
+		}
 	}
 	return result, nil
 }
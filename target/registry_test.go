@@ -2,6 +2,7 @@ package target
 
 import (
 	"context"
+	"strings"
 	"testing"
 )
 
@@ -78,7 +79,7 @@ func TestResolveSingle(t *testing.T) {
 	if len(resolved) != 1 {
 		t.Fatalf("expected 1 target, got %d", len(resolved))
 	}
-	if _, ok := resolved["api"]; !ok {
+	if resolved[0].Name != "api" {
 		t.Fatal("expected api target in resolved set")
 	}
 }
@@ -94,6 +95,33 @@ func TestResolveMultiple(t *testing.T) {
 	}
 }
 
+func TestResolveKeepsRegistrationOrder(t *testing.T) {
+	r := newTestRegistry()
+	resolved, err := r.Resolve("scheduler, api")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resolved[0].Name != "api" || resolved[1].Name != "scheduler" {
+		t.Fatalf("expected api then scheduler, got %s then %s", resolved[0].Name, resolved[1].Name)
+	}
+}
+
+func TestRegisterOptions(t *testing.T) {
+	r := New()
+	r.Register("api", noop, WithRestart(RestartAlways), Critical())
+	r.Register("worker", noop)
+	resolved, err := r.Resolve("all")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resolved[0].Restart != RestartAlways || !resolved[0].Critical {
+		t.Fatalf("expected options applied, got %+v", resolved[0])
+	}
+	if resolved[1].Restart != RestartNever || resolved[1].Critical {
+		t.Fatalf("expected defaults, got %+v", resolved[1])
+	}
+}
+
 func TestResolveUnknown(t *testing.T) {
 	r := newTestRegistry()
 	_, err := r.Resolve("bogus")
@@ -120,3 +148,58 @@ func TestDuplicateRegisterPanics(t *testing.T) {
 	}()
 	r.Register("api", noop)
 }
+
+func TestResolveOrdersDependenciesFirst(t *testing.T) {
+	r := New()
+	r.Register("proxy", noop, DependsOn("tunnel"))
+	r.Register("api", noop)
+	r.Register("tunnel", noop, DependsOn("api"))
+
+	resolved, err := r.Resolve("all")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var names []string
+	for _, spec := range resolved {
+		names = append(names, spec.Name)
+	}
+	if strings.Join(names, ",") != "api,tunnel,proxy" {
+		t.Fatalf("expected api,tunnel,proxy, got %v", names)
+	}
+
+	resolved, err = r.Resolve("proxy")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(resolved) != 1 {
+		t.Fatalf("dependencies should not be selected implicitly, got %d targets", len(resolved))
+	}
+}
+
+func TestResolveUnknownDependency(t *testing.T) {
+	r := New()
+	r.Register("proxy", noop, DependsOn("tunnel"))
+	if _, err := r.Resolve("proxy"); err == nil {
+		t.Fatal("expected error for unknown dependency")
+	}
+}
+
+func TestRegisterRejectsCycles(t *testing.T) {
+	for name, register := range map[string]func(r *Registry){
+		"self": func(r *Registry) { r.Register("a", noop, DependsOn("a")) },
+		"indirect": func(r *Registry) {
+			r.Register("a", noop, DependsOn("b"))
+			r.Register("b", noop, DependsOn("c"))
+			r.Register("c", noop, DependsOn("a"))
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Fatal("expected panic on dependency cycle")
+				}
+			}()
+			register(New())
+		})
+	}
+}
@@ -0,0 +1,411 @@
+package target
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/flockiot/flock-api/config"
+	"github.com/flockiot/flock-api/health"
+	"github.com/flockiot/flock-api/metrics"
+)
+
+type RestartPolicy string
+
+const (
+	RestartNever     RestartPolicy = "never"
+	RestartOnFailure RestartPolicy = "on-failure"
+	RestartAlways    RestartPolicy = "always"
+)
+
+func ParseRestartPolicy(s string) (RestartPolicy, error) {
+	switch p := RestartPolicy(s); p {
+	case RestartNever, RestartOnFailure, RestartAlways:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unknown restart policy %q (valid: never, on-failure, always)", s)
+	}
+}
+
+func (p RestartPolicy) restarts(err error) bool {
+	switch p {
+	case RestartAlways:
+		return true
+	case RestartOnFailure:
+		return err != nil
+	default:
+		return false
+	}
+}
+
+type State string
+
+const (
+	StateStarting State = "starting"
+	StateRunning  State = "running"
+	StateBackoff  State = "backoff"
+	StateStopped  State = "stopped"
+	StateFailed   State = "failed"
+)
+
+type Status struct {
+	Name      string    `json:"name"`
+	State     State     `json:"state"`
+	Critical  bool      `json:"critical"`
+	Restarts  int       `json:"restarts"`
+	LastError string    `json:"last_error,omitempty"`
+	Since     time.Time `json:"since"`
+}
+
+type Supervisor struct {
+	cfg  config.SupervisorConfig
+	deps *Deps
+
+	mu       sync.Mutex
+	statuses map[string]*Status
+	order    []string
+}
+
+func NewSupervisor(cfg config.SupervisorConfig, deps *Deps) *Supervisor {
+	return &Supervisor{
+		cfg:      cfg,
+		deps:     deps,
+		statuses: make(map[string]*Status),
+	}
+}
+
+const readyPollInterval = 100 * time.Millisecond
+
+// After ErrShutdownTimeout the caller should exit without waiting for targets.
+var ErrShutdownTimeout = errors.New("targets did not stop before the shutdown deadline")
+
+type running struct {
+	spec   Spec
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func (s *Supervisor) Run(ctx context.Context, specs []Spec) error {
+	specs, err := s.applyOverrides(specs)
+	if err != nil {
+		return err
+	}
+
+	runCtx, stop := context.WithCancel(ctx)
+	defer stop()
+
+	var (
+		failOnce sync.Once
+		failure  error
+	)
+	fail := func(err error) {
+		failOnce.Do(func() {
+			failure = err
+			stop()
+		})
+	}
+
+	selected := make(map[string]bool, len(specs))
+	for _, spec := range specs {
+		selected[spec.Name] = true
+		s.track(spec)
+		if s.deps.Health != nil {
+			s.deps.Health.Register("target/"+spec.Name, s.check(spec.Name))
+		}
+	}
+
+	var (
+		started []*running
+		wg      sync.WaitGroup
+	)
+	for _, spec := range specs {
+		if err := s.waitForDependencies(runCtx, spec, selected); err != nil {
+			if runCtx.Err() == nil {
+				fail(err)
+			}
+			break
+		}
+
+		// Targets outlive runCtx so that shutdown can stop them one by one.
+		targetCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		r := &running{spec: spec, cancel: cancel, done: make(chan struct{})}
+		started = append(started, r)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer close(r.done)
+			err := s.supervise(targetCtx, spec)
+			if err != nil && spec.Critical {
+				fail(fmt.Errorf("critical target %s failed: %w", spec.Name, err))
+			}
+		}()
+	}
+
+	exited := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(exited)
+	}()
+	select {
+	case <-runCtx.Done():
+	case <-exited:
+	}
+
+	if stuck := s.shutdown(started); len(stuck) > 0 {
+		return errors.Join(failure, fmt.Errorf("%w: %s", ErrShutdownTimeout, strings.Join(stuck, ", ")))
+	}
+	return failure
+}
+
+func (s *Supervisor) waitForDependencies(ctx context.Context, spec Spec, selected map[string]bool) error {
+	if s.cfg.StartTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.cfg.StartTimeout)
+		defer cancel()
+	}
+
+	for _, dep := range spec.DependsOn {
+		if !selected[dep] {
+			continue
+		}
+		for {
+			err := s.ready(ctx, dep)
+			if err == nil {
+				break
+			}
+			if st := s.status(dep); st.State == StateStopped || st.State == StateFailed {
+				return fmt.Errorf("target %s: dependency %s is %s", spec.Name, dep, st.State)
+			}
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("target %s: dependency %s not ready after %s: %w", spec.Name, dep, s.cfg.StartTimeout, err)
+			case <-time.After(readyPollInterval):
+			}
+		}
+	}
+	return nil
+}
+
+// ready also runs the checks a target registered under its own name, such as
+// "webhooks/dispatcher".
+func (s *Supervisor) ready(ctx context.Context, name string) error {
+	if st := s.status(name); st.State != StateRunning {
+		return fmt.Errorf("target is %s", st.State)
+	}
+	if s.deps.Health == nil {
+		return nil
+	}
+	results, ok := s.deps.Health.RunPrefix(ctx, name+"/")
+	if ok {
+		return nil
+	}
+	for _, res := range results {
+		if !res.OK() {
+			return fmt.Errorf("%s: %w", res.Name, res.Error)
+		}
+	}
+	return nil
+}
+
+// Once ShutdownTimeout has passed, shutdown cancels the remaining targets
+// together and returns those still running.
+func (s *Supervisor) shutdown(started []*running) (stuck []string) {
+	if s.deps.Health != nil && s.cfg.DrainDelay > 0 && s.anyRunning(started) {
+		s.deps.Health.Drain()
+		slog.Info("draining before shutdown", "delay", s.cfg.DrainDelay)
+		time.Sleep(s.cfg.DrainDelay)
+	}
+
+	deadline := time.NewTimer(s.cfg.ShutdownTimeout)
+	defer deadline.Stop()
+
+	for i := len(started) - 1; i >= 0; i-- {
+		started[i].cancel()
+		select {
+		case <-started[i].done:
+			continue
+		default:
+		}
+		select {
+		case <-started[i].done:
+		case <-deadline.C:
+			for _, r := range started[:i+1] {
+				r.cancel()
+				select {
+				case <-r.done:
+				default:
+					stuck = append(stuck, r.spec.Name)
+				}
+			}
+			slog.Error("targets failed to stop before the shutdown deadline",
+				"timeout", s.cfg.ShutdownTimeout, "targets", stuck)
+			return stuck
+		}
+	}
+	return nil
+}
+
+func (s *Supervisor) anyRunning(started []*running) bool {
+	for _, r := range started {
+		select {
+		case <-r.done:
+		default:
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Supervisor) Statuses() []Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]Status, 0, len(s.order))
+	for _, name := range s.order {
+		out = append(out, *s.statuses[name])
+	}
+	return out
+}
+
+func (s *Supervisor) check(name string) health.Check {
+	return func(context.Context) error {
+		if st := s.status(name); st.State != StateRunning {
+			return fmt.Errorf("target is %s", st.State)
+		}
+		return nil
+	}
+}
+
+func (s *Supervisor) applyOverrides(specs []Spec) ([]Spec, error) {
+	out := make([]Spec, len(specs))
+	for i, spec := range specs {
+		if raw, ok := s.cfg.Restart[spec.Name]; ok {
+			p, err := ParseRestartPolicy(raw)
+			if err != nil {
+				return nil, fmt.Errorf("target %s: %w", spec.Name, err)
+			}
+			spec.Restart = p
+		}
+		if critical, ok := s.cfg.Critical[spec.Name]; ok {
+			spec.Critical = critical
+		}
+		out[i] = spec
+	}
+	return out, nil
+}
+
+func (s *Supervisor) supervise(ctx context.Context, spec Spec) error {
+	log := slog.With("target", spec.Name)
+	backoff := s.cfg.Backoff
+	restarts := 0
+	for {
+		s.setState(spec.Name, StateRunning, nil)
+		metrics.SetTargetUp(spec.Name, true)
+		started := time.Now()
+		err := runTarget(ctx, spec, s.deps)
+		metrics.SetTargetUp(spec.Name, false)
+
+		if ctx.Err() != nil {
+			if err != nil {
+				log.Error("target stopped with error", "error", err)
+			}
+			s.setState(spec.Name, StateStopped, err)
+			return nil
+		}
+		if err != nil {
+			log.Error("target failed", "error", err)
+		} else {
+			log.Info("target exited")
+		}
+
+		if !spec.Restart.restarts(err) {
+			if err != nil {
+				s.setState(spec.Name, StateFailed, err)
+				return err
+			}
+			s.setState(spec.Name, StateStopped, nil)
+			return nil
+		}
+
+		if time.Since(started) > s.cfg.MaxBackoff {
+			backoff = s.cfg.Backoff
+			restarts = 0
+		}
+		if s.cfg.MaxRestarts > 0 && restarts >= s.cfg.MaxRestarts {
+			if err == nil {
+				err = errors.New("target keeps exiting")
+			}
+			err = fmt.Errorf("giving up after %d restarts: %w", restarts, err)
+			log.Error("target failed permanently", "error", err)
+			s.setState(spec.Name, StateFailed, err)
+			return err
+		}
+
+		s.setState(spec.Name, StateBackoff, err)
+		log.Warn("restarting target", "backoff", backoff)
+		select {
+		case <-ctx.Done():
+			s.setState(spec.Name, StateStopped, err)
+			return nil
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, s.cfg.MaxBackoff)
+		restarts++
+		s.countRestart(spec.Name)
+		metrics.IncTargetRestarts(spec.Name)
+	}
+}
+
+// runTarget leaves a panicking target to its restart policy instead of taking
+// down the process.
+func runTarget(ctx context.Context, spec Spec, deps *Deps) (err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = fmt.Errorf("panic: %v\n%s", v, debug.Stack())
+		}
+	}()
+	return spec.Start(ctx, deps)
+}
+
+func (s *Supervisor) track(spec Spec) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.statuses[spec.Name]; !ok {
+		s.order = append(s.order, spec.Name)
+	}
+	s.statuses[spec.Name] = &Status{
+		Name:     spec.Name,
+		State:    StateStarting,
+		Critical: spec.Critical,
+		Since:    time.Now(),
+	}
+}
+
+func (s *Supervisor) setState(name string, state State, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := s.statuses[name]
+	if st.State != state {
+		st.State = state
+		st.Since = time.Now()
+	}
+	if err != nil {
+		st.LastError = err.Error()
+	}
+}
+
+func (s *Supervisor) status(name string) Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return *s.statuses[name]
+}
+
+func (s *Supervisor) countRestart(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.statuses[name].Restarts++
+}
@@ -0,0 +1,333 @@
+package target
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/flockiot/flock-api/config"
+	"github.com/flockiot/flock-api/health"
+)
+
+func testSupervisor() *Supervisor {
+	return NewSupervisor(config.SupervisorConfig{
+		Backoff:         time.Millisecond,
+		MaxBackoff:      10 * time.Millisecond,
+		MaxRestarts:     3,
+		ShutdownTimeout: time.Second,
+	}, &Deps{})
+}
+
+func waitRunning(t *testing.T, s *Supervisor, name string) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		for _, st := range s.Statuses() {
+			if st.Name == name && st.State == StateRunning {
+				return
+			}
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("target %s did not start", name)
+}
+
+func failing(calls *atomic.Int32) StartFunc {
+	return func(context.Context, *Deps) error {
+		calls.Add(1)
+		return errors.New("boom")
+	}
+}
+
+func TestSupervisorRestartNever(t *testing.T) {
+	var calls atomic.Int32
+	s := testSupervisor()
+	err := s.Run(context.Background(), []Spec{{Name: "worker", Start: failing(&calls), Restart: RestartNever}})
+	if err != nil {
+		t.Fatalf("non-critical failure should not fail Run: %v", err)
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("expected 1 call, got %d", calls.Load())
+	}
+	st := s.Statuses()[0]
+	if st.State != StateFailed || st.LastError != "boom" {
+		t.Fatalf("expected failed with last error, got %+v", st)
+	}
+}
+
+func TestSupervisorRestartOnFailureGivesUp(t *testing.T) {
+	var calls atomic.Int32
+	s := testSupervisor()
+	_ = s.Run(context.Background(), []Spec{{Name: "worker", Start: failing(&calls), Restart: RestartOnFailure}})
+	if calls.Load() != 4 {
+		t.Fatalf("expected the first run plus 3 restarts, got %d", calls.Load())
+	}
+	if st := s.Statuses()[0]; st.State != StateFailed || st.Restarts != 3 {
+		t.Fatalf("expected failed after 3 restarts, got %+v", st)
+	}
+}
+
+func TestSupervisorRestartOnFailureIgnoresCleanExit(t *testing.T) {
+	var calls atomic.Int32
+	s := testSupervisor()
+	_ = s.Run(context.Background(), []Spec{{Name: "worker", Restart: RestartOnFailure, Start: func(context.Context, *Deps) error {
+		calls.Add(1)
+		return nil
+	}}})
+	if calls.Load() != 1 {
+		t.Fatalf("expected no restart after a clean exit, got %d calls", calls.Load())
+	}
+	if st := s.Statuses()[0]; st.State != StateStopped {
+		t.Fatalf("expected stopped, got %s", st.State)
+	}
+}
+
+func TestSupervisorRestartAlways(t *testing.T) {
+	var calls atomic.Int32
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := testSupervisor()
+	s.cfg.MaxRestarts = 0
+	err := s.Run(ctx, []Spec{{Name: "worker", Restart: RestartAlways, Start: func(context.Context, *Deps) error {
+		if calls.Add(1) == 3 {
+			cancel()
+		}
+		return nil
+	}}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls.Load() != 3 {
+		t.Fatalf("expected 3 calls, got %d", calls.Load())
+	}
+}
+
+func TestSupervisorCriticalFailureStopsOthers(t *testing.T) {
+	var calls atomic.Int32
+	stopped := make(chan struct{})
+	s := testSupervisor()
+	err := s.Run(context.Background(), []Spec{
+		{Name: "api", Start: failing(&calls), Restart: RestartNever, Critical: true},
+		{Name: "worker", Start: func(ctx context.Context, _ *Deps) error {
+			<-ctx.Done()
+			close(stopped)
+			return nil
+		}},
+	})
+	if err == nil {
+		t.Fatal("expected critical failure to be returned")
+	}
+	select {
+	case <-stopped:
+	default:
+		t.Fatal("expected the other target to be cancelled")
+	}
+	statuses := s.Statuses()
+	if statuses[0].State != StateFailed || statuses[1].State != StateStopped {
+		t.Fatalf("unexpected states: %+v", statuses)
+	}
+}
+
+func TestSupervisorRecoversPanics(t *testing.T) {
+	s := testSupervisor()
+	err := s.Run(context.Background(), []Spec{{Name: "api", Critical: true, Restart: RestartNever, Start: func(context.Context, *Deps) error {
+		panic("oops")
+	}}})
+	if err == nil {
+		t.Fatal("expected panic to surface as an error")
+	}
+}
+
+func TestSupervisorOverrides(t *testing.T) {
+	var calls atomic.Int32
+	s := testSupervisor()
+	s.cfg.Restart = map[string]string{"worker": "on-failure"}
+	s.cfg.Critical = map[string]bool{"worker": true}
+	err := s.Run(context.Background(), []Spec{{Name: "worker", Start: failing(&calls), Restart: RestartNever}})
+	if err == nil {
+		t.Fatal("expected worker to be critical")
+	}
+	if calls.Load() != 4 {
+		t.Fatalf("expected restarts from the override, got %d calls", calls.Load())
+	}
+
+	s.cfg.Restart = map[string]string{"worker": "sometimes"}
+	if err := s.Run(context.Background(), []Spec{{Name: "worker", Start: noop}}); err == nil {
+		t.Fatal("expected invalid policy to be rejected")
+	}
+}
+
+func TestSupervisorRegistersHealthChecks(t *testing.T) {
+	checks := health.NewRegistry()
+	s := testSupervisor()
+	s.deps = &Deps{Health: checks}
+	ctx, cancel := context.WithCancel(context.Background())
+	running := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- s.Run(ctx, []Spec{{Name: "worker", Start: func(ctx context.Context, _ *Deps) error {
+			close(running)
+			<-ctx.Done()
+			return nil
+		}}})
+	}()
+
+	<-running
+	if results, ok := checks.Run(context.Background()); !ok || results[0].Name != "target/worker" {
+		t.Fatalf("expected passing target/worker check, got ok=%v %+v", ok, results)
+	}
+	cancel()
+	<-done
+	if _, ok := checks.Run(context.Background()); ok {
+		t.Fatal("expected a stopped target to fail its check")
+	}
+}
+
+func TestSupervisorStartsAndStopsInDependencyOrder(t *testing.T) {
+	checks := health.NewRegistry()
+	var (
+		mu     sync.Mutex
+		events []string
+	)
+	record := func(e string) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, e)
+	}
+	var tunnelReady atomic.Bool
+	checks.Register("tunnel/listener", func(context.Context) error {
+		if !tunnelReady.Load() {
+			return errors.New("not bound")
+		}
+		return nil
+	})
+	target := func(name string, ready func()) StartFunc {
+		return func(ctx context.Context, _ *Deps) error {
+			record("start " + name)
+			if ready != nil {
+				time.Sleep(20 * time.Millisecond)
+				ready()
+			}
+			<-ctx.Done()
+			record("stop " + name)
+			return nil
+		}
+	}
+
+	s := testSupervisor()
+	s.deps = &Deps{Health: checks}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- s.Run(ctx, []Spec{
+			{Name: "tunnel", Start: target("tunnel", func() { tunnelReady.Store(true) })},
+			{Name: "proxy", Start: target("proxy", nil), DependsOn: []string{"tunnel"}},
+		})
+	}()
+
+	waitRunning(t, s, "proxy")
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "start tunnel,start proxy,stop proxy,stop tunnel"
+	if got := strings.Join(events, ","); got != want {
+		t.Fatalf("expected %s, got %s", want, got)
+	}
+}
+
+func TestSupervisorDependencyNotReady(t *testing.T) {
+	checks := health.NewRegistry()
+	checks.Register("tunnel/listener", func(context.Context) error { return errors.New("not bound") })
+	var proxyStarted atomic.Bool
+
+	s := testSupervisor()
+	s.deps = &Deps{Health: checks}
+	s.cfg.StartTimeout = 250 * time.Millisecond
+	err := s.Run(context.Background(), []Spec{
+		{Name: "tunnel", Start: func(ctx context.Context, _ *Deps) error { <-ctx.Done(); return nil }},
+		{Name: "proxy", DependsOn: []string{"tunnel"}, Start: func(context.Context, *Deps) error {
+			proxyStarted.Store(true)
+			return nil
+		}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "tunnel/listener: not bound") {
+		t.Fatalf("expected readiness timeout naming the failing check, got %v", err)
+	}
+	if proxyStarted.Load() {
+		t.Fatal("proxy should not start before its dependency is ready")
+	}
+	if st := s.status("tunnel"); st.State != StateStopped {
+		t.Fatalf("expected tunnel to be stopped, got %s", st.State)
+	}
+}
+
+func TestSupervisorShutdownDeadline(t *testing.T) {
+	s := testSupervisor()
+	s.cfg.ShutdownTimeout = 20 * time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+	stuck := make(chan struct{})
+	defer close(stuck)
+
+	done := make(chan error)
+	go func() {
+		done <- s.Run(ctx, []Spec{{Name: "stuck", Start: func(context.Context, *Deps) error {
+			<-stuck
+			return nil
+		}}})
+	}()
+	waitRunning(t, s, "stuck")
+	cancel()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrShutdownTimeout) || !strings.Contains(err.Error(), "stuck") {
+			t.Fatalf("expected ErrShutdownTimeout naming the stuck target, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Run should return once the shutdown deadline passes")
+	}
+}
+
+func TestSupervisorDrainsBeforeStopping(t *testing.T) {
+	checks := health.NewRegistry()
+	s := testSupervisor()
+	s.deps = &Deps{Health: checks}
+	s.cfg.DrainDelay = 50 * time.Millisecond
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var readyWhileStopping atomic.Bool
+	done := make(chan error)
+	go func() {
+		done <- s.Run(ctx, []Spec{{Name: "api", Start: func(ctx context.Context, _ *Deps) error {
+			<-ctx.Done()
+			_, ok := checks.Run(context.Background())
+			readyWhileStopping.Store(ok)
+			return nil
+		}}})
+	}()
+	waitRunning(t, s, "api")
+	if _, ok := checks.Run(context.Background()); !ok {
+		t.Fatal("expected ready before shutdown")
+	}
+
+	cancel()
+	time.Sleep(10 * time.Millisecond)
+	if s.status("api").State != StateRunning {
+		t.Fatal("target should keep running while draining")
+	}
+	if _, ok := checks.Run(context.Background()); ok {
+		t.Fatal("expected readiness to fail while draining")
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if readyWhileStopping.Load() {
+		t.Fatal("expected readiness to stay failed while stopping")
+	}
+}
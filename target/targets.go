@@ -4,25 +4,51 @@ import (
 	"context"
 	"log/slog"
 
+	"golang.org/x/sync/errgroup"
+
 	"github.com/flockiot/flock-api/api"
+	"github.com/flockiot/flock-api/gateway"
+	"github.com/flockiot/flock-api/rpc"
+	"github.com/flockiot/flock-api/webhook"
 )
 
 func DefaultRegistry() *Registry {
 	r := New()
-	r.Register("api", apiStart)
+	r.Register("api", apiStart, WithRestart(RestartOnFailure), Critical())
 	r.Register("ingester", placeholder("ingester"))
 	r.Register("scheduler", placeholder("scheduler"))
 	r.Register("builder", placeholder("builder"))
 	r.Register("delta", placeholder("delta"))
 	r.Register("registry-proxy", placeholder("registry-proxy"))
 	r.Register("tunnel", placeholder("tunnel"))
-	r.Register("proxy", placeholder("proxy"))
-	r.Register("events-gateway", placeholder("events-gateway"))
+	r.Register("proxy", placeholder("proxy"), DependsOn("tunnel"))
+	r.Register("events-gateway", eventsGatewayStart, WithRestart(RestartOnFailure), Critical())
+	r.Register("webhooks", webhooksStart, WithRestart(RestartOnFailure))
 	return r
 }
 
+// apiStart shares one rate limiter between HTTP and gRPC.
 func apiStart(ctx context.Context, deps *Deps) error {
-	return api.Start(ctx, deps.Config, deps.DB)
+	g, ctx := errgroup.WithContext(ctx)
+	limiter, err := api.NewLimiter(ctx, deps.Config, deps.DB)
+	if err != nil {
+		return err
+	}
+	g.Go(func() error {
+		return api.Start(ctx, deps.Config, deps.DB, deps.Replicas, limiter, deps.Health)
+	})
+	g.Go(func() error {
+		return rpc.Start(ctx, deps.Config, deps.DB, deps.Replicas, limiter, deps.Health)
+	})
+	return g.Wait()
+}
+
+func eventsGatewayStart(ctx context.Context, deps *Deps) error {
+	return gateway.Start(ctx, deps.Config, deps.DB, deps.Health)
+}
+
+func webhooksStart(ctx context.Context, deps *Deps) error {
+	return webhook.Start(ctx, deps.Config, deps.DB, deps.Health)
 }
 
 func placeholder(name string) StartFunc {
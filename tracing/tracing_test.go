@@ -0,0 +1,105 @@
+package tracing
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/flockiot/flock-api/config"
+)
+
+func TestSetupFileExporter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces.json")
+	shutdown, err := Setup(context.Background(), config.TracingConfig{
+		Exporter:    "file",
+		File:        path,
+		SampleRatio: 1,
+		ServiceName: "flock-test",
+	})
+	if err != nil {
+		t.Fatalf("Setup() error: %v", err)
+	}
+	t.Cleanup(func() { otel.SetTracerProvider(noop.NewTracerProvider()) })
+
+	_, span := Tracer().Start(context.Background(), "test-span")
+	span.End()
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown error: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"Name":"test-span"`) || !strings.Contains(string(data), "flock-test") {
+		t.Fatalf("expected exported span in file, got: %s", data)
+	}
+}
+
+func TestSetupRejectsBadExporter(t *testing.T) {
+	for _, cfg := range []config.TracingConfig{
+		{Exporter: "zipkin"},
+		{Exporter: "file"},
+	} {
+		if _, err := Setup(context.Background(), cfg); err == nil {
+			t.Errorf("expected error for %+v", cfg)
+		}
+	}
+}
+
+func TestSetupNone(t *testing.T) {
+	shutdown, err := Setup(context.Background(), config.TracingConfig{Exporter: "none"})
+	if err != nil {
+		t.Fatalf("Setup() error: %v", err)
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatalf("shutdown error: %v", err)
+	}
+}
+
+func TestQueryTracer(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(noop.NewTracerProvider()) })
+
+	var qt QueryTracer
+
+	// Without a parent span the query is not traced.
+	ctx := qt.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: "SELECT 1"})
+	qt.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{})
+	if n := len(recorder.Ended()); n != 0 {
+		t.Fatalf("expected no spans without a parent, got %d", n)
+	}
+
+	ctx, parent := Tracer().Start(context.Background(), "parent")
+	qctx := qt.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: "  select id from organizations"})
+	qt.TraceQueryEnd(qctx, nil, pgx.TraceQueryEndData{Err: pgx.ErrNoRows})
+	parent.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	q := spans[0]
+	if q.Name() != "SELECT" {
+		t.Fatalf("expected span name SELECT, got %q", q.Name())
+	}
+	if q.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Fatal("query span should be a child of the active span")
+	}
+	if q.SpanKind() != trace.SpanKindClient {
+		t.Fatalf("expected client span, got %v", q.SpanKind())
+	}
+	if len(q.Events()) != 0 {
+		t.Fatal("ErrNoRows should not be recorded as an error")
+	}
+}
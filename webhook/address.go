@@ -0,0 +1,36 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"syscall"
+)
+
+// ErrAddressNotAllowed stops a subscription from reaching services on the
+// dispatcher's network.
+var ErrAddressNotAllowed = errors.New("destination address is not allowed")
+
+func CheckAddr(ip netip.Addr) error {
+	ip = ip.Unmap()
+	if !ip.IsValid() || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return ErrAddressNotAllowed
+	}
+	return nil
+}
+
+// dialControl runs after DNS resolution, so a public name cannot resolve to
+// an internal address.
+func dialControl(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("parsing dial address: %w", err)
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return fmt.Errorf("parsing dial address: %w", err)
+	}
+	return CheckAddr(ip)
+}
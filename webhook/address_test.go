@@ -0,0 +1,22 @@
+package webhook
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestCheckAddr(t *testing.T) {
+	for _, addr := range []string{
+		"127.0.0.1", "::1", "10.1.2.3", "172.16.0.1", "192.168.1.1", "fd00::1",
+		"169.254.169.254", "fe80::1", "0.0.0.0", "::", "224.0.0.1", "::ffff:127.0.0.1",
+	} {
+		if err := CheckAddr(netip.MustParseAddr(addr)); err == nil {
+			t.Errorf("CheckAddr(%s) = nil, want an error", addr)
+		}
+	}
+	for _, addr := range []string{"93.184.216.34", "2606:2800:220:1:248:1893:25c8:1946"} {
+		if err := CheckAddr(netip.MustParseAddr(addr)); err != nil {
+			t.Errorf("CheckAddr(%s) = %v, want nil", addr, err)
+		}
+	}
+}
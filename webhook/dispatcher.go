@@ -0,0 +1,239 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/flockiot/flock-api/config"
+	"github.com/flockiot/flock-api/health"
+	"github.com/flockiot/flock-api/logging"
+	"github.com/flockiot/flock-api/repository"
+	"github.com/flockiot/flock-api/tracing"
+	"github.com/flockiot/flock-api/version"
+)
+
+type store interface {
+	ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]*repository.ClaimedDelivery, error)
+	RecordAttempt(ctx context.Context, d *repository.ClaimedDelivery, o repository.AttemptOutcome) error
+}
+
+type Dispatcher struct {
+	cfg    config.WebhookConfig
+	store  store
+	client *http.Client
+
+	lastPoll atomic.Int64 // unix nanoseconds of the last successful claim
+}
+
+func NewDispatcher(cfg config.WebhookConfig, store store) *Dispatcher {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// A proxy would be dialled instead of the subscriber, bypassing the
+	// address check.
+	transport.Proxy = nil
+	transport.DialContext = (&net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   dialControl,
+	}).DialContext
+	return &Dispatcher{
+		cfg:   cfg,
+		store: store,
+		client: &http.Client{
+			Timeout:   cfg.Timeout,
+			Transport: transport,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+func Start(ctx context.Context, cfg *config.Config, pool *pgxpool.Pool, checks *health.Registry) error {
+	if pool == nil {
+		return fmt.Errorf("webhook dispatcher requires a database")
+	}
+	d := NewDispatcher(cfg.Webhooks, repository.NewWebhookRepository(pool, nil))
+	checks.Register("webhooks/dispatcher", d.Check)
+	slog.Info("webhook dispatcher started")
+	return d.Run(ctx)
+}
+
+func (d *Dispatcher) Check(_ context.Context) error {
+	last := d.lastPoll.Load()
+	if last == 0 {
+		return fmt.Errorf("webhook dispatcher has not polled yet")
+	}
+	stale := max(10*d.cfg.PollInterval, 2*d.cfg.Timeout)
+	if since := time.Since(time.Unix(0, last)); since > stale {
+		return fmt.Errorf("webhook dispatcher last polled %s ago", since.Round(time.Second))
+	}
+	return nil
+}
+
+func (d *Dispatcher) Run(ctx context.Context) error {
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+	for {
+		d.drain(ctx)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (d *Dispatcher) drain(ctx context.Context) {
+	lease := 2 * d.cfg.Timeout
+	for ctx.Err() == nil {
+		batch, err := d.store.ClaimDue(ctx, d.cfg.Concurrency, lease)
+		if err != nil {
+			if ctx.Err() == nil {
+				slog.Error("claiming webhook deliveries failed", "error", err)
+			}
+			return
+		}
+		d.lastPoll.Store(time.Now().UnixNano())
+
+		var wg sync.WaitGroup
+		for _, del := range batch {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				d.deliver(ctx, del)
+			}()
+		}
+		wg.Wait()
+
+		if len(batch) < d.cfg.Concurrency {
+			return
+		}
+	}
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, del *repository.ClaimedDelivery) {
+	ctx, span := tracing.Tracer().Start(ctx, "webhook delivery",
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("webhook.delivery_id", del.ID),
+			attribute.String("webhook.subscription_id", del.SubscriptionID),
+			attribute.String("webhook.event_type", del.EventType),
+			attribute.Int("webhook.attempt", del.Attempts+1),
+		),
+	)
+	defer span.End()
+
+	outcome := d.attempt(ctx, del)
+	if outcome.StatusCode != 0 {
+		span.SetAttributes(semconv.HTTPResponseStatusCode(outcome.StatusCode))
+	}
+	if !outcome.Succeeded {
+		span.SetStatus(codes.Error, outcome.Error)
+	}
+	// Recorded even during shutdown, or the lease would hide the outcome.
+	if err := d.store.RecordAttempt(context.WithoutCancel(ctx), del, outcome); err != nil {
+		slog.ErrorContext(ctx, "recording webhook attempt failed", "delivery_id", del.ID, "error", err)
+	}
+}
+
+func (d *Dispatcher) attempt(ctx context.Context, del *repository.ClaimedDelivery) repository.AttemptOutcome {
+	start := time.Now()
+	outcome := repository.AttemptOutcome{DisableAfter: d.cfg.DisableAfter}
+
+	status, err := d.send(ctx, del, start)
+	outcome.Duration = time.Since(start)
+	outcome.StatusCode = status
+	switch {
+	case err != nil:
+		// last_error is shown to subscribers, and the raw error can name
+		// internal hosts.
+		outcome.Error = deliveryError(err)
+	case status < 200 || status > 299:
+		outcome.Error = fmt.Sprintf("unexpected status %d", status)
+	default:
+		outcome.Succeeded = true
+	}
+
+	logger := logging.FromContext(ctx).With("delivery_id", del.ID, "subscription_id", del.SubscriptionID,
+		"event_type", del.EventType, "attempt", del.Attempts+1, "status", status)
+	if err != nil {
+		logger = logger.With("cause", err)
+	}
+	if outcome.Succeeded {
+		logger.Info("webhook delivered", "duration_ms", outcome.Duration.Milliseconds())
+		return outcome
+	}
+
+	if next := del.Attempts + 1; next < d.cfg.MaxAttempts {
+		retryAt := time.Now().Add(Backoff(d.cfg.RetryBase, d.cfg.RetryMax, next))
+		outcome.RetryAt = &retryAt
+		logger.Warn("webhook delivery failed, will retry", "error", outcome.Error, "retry_at", retryAt)
+	} else {
+		logger.Error("webhook delivery failed permanently", "error", outcome.Error)
+	}
+	return outcome
+}
+
+func (d *Dispatcher) send(ctx context.Context, del *repository.ClaimedDelivery, now time.Time) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, del.URL, bytes.NewReader(del.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "flock-webhooks/"+version.Get())
+	req.Header.Set(HeaderID, del.ID)
+	req.Header.Set(HeaderEvent, del.EventType)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set(HeaderSignature, Sign(del.Secret, now, del.Payload))
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	return resp.StatusCode, nil
+}
+
+func deliveryError(err error) string {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, ErrAddressNotAllowed):
+		return ErrAddressNotAllowed.Error()
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return "request timed out"
+	default:
+		return "connection failed"
+	}
+}
+
+// Backoff counts attempts from 1.
+func Backoff(base, max time.Duration, attempt int) time.Duration {
+	d := base
+	for i := 1; i < attempt; i++ {
+		d *= 2
+		if d >= max {
+			return max
+		}
+	}
+	return min(d, max)
+}
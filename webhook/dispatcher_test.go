@@ -0,0 +1,202 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/flockiot/flock-api/config"
+	"github.com/flockiot/flock-api/repository"
+)
+
+type fakeStore struct {
+	mu       sync.Mutex
+	due      []*repository.ClaimedDelivery
+	outcomes map[string]repository.AttemptOutcome
+}
+
+func (f *fakeStore) ClaimDue(_ context.Context, limit int, _ time.Duration) ([]*repository.ClaimedDelivery, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := min(limit, len(f.due))
+	batch := f.due[:n]
+	f.due = f.due[n:]
+	return batch, nil
+}
+
+func (f *fakeStore) RecordAttempt(_ context.Context, d *repository.ClaimedDelivery, o repository.AttemptOutcome) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.outcomes[d.ID] = o
+	return nil
+}
+
+func testConfig() config.WebhookConfig {
+	return config.WebhookConfig{
+		PollInterval: time.Hour,
+		Timeout:      time.Second,
+		Concurrency:  2,
+		MaxAttempts:  3,
+		RetryBase:    time.Second,
+		RetryMax:     time.Minute,
+		DisableAfter: 10,
+	}
+}
+
+// newTestDispatcher returns a dispatcher that may deliver to the loopback
+// receivers the tests start.
+func newTestDispatcher(store store) *Dispatcher {
+	d := NewDispatcher(testConfig(), store)
+	d.client.Transport = http.DefaultTransport
+	return d
+}
+
+func delivery(id, url string, attempts int) *repository.ClaimedDelivery {
+	return &repository.ClaimedDelivery{
+		WebhookDelivery: repository.WebhookDelivery{
+			ID:             id,
+			SubscriptionID: "sub",
+			EventType:      "organization.created",
+			Payload:        json.RawMessage(`{"type":"organization.created"}`),
+			Attempts:       attempts,
+		},
+		URL:    url,
+		Secret: "whsec_test",
+	}
+}
+
+func TestDispatcherDeliversSignedPayload(t *testing.T) {
+	var gotEvent string
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := Verify("whsec_test", r.Header.Get(HeaderSignature), r.Header.Get(HeaderTimestamp), body, time.Minute); err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		gotEvent = r.Header.Get(HeaderEvent)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	store := &fakeStore{
+		due:      []*repository.ClaimedDelivery{delivery("d1", receiver.URL, 0)},
+		outcomes: map[string]repository.AttemptOutcome{},
+	}
+	newTestDispatcher(store).drain(context.Background())
+
+	o := store.outcomes["d1"]
+	if !o.Succeeded {
+		t.Fatalf("expected success, got %+v", o)
+	}
+	if o.StatusCode != http.StatusNoContent {
+		t.Fatalf("expected status 204, got %d", o.StatusCode)
+	}
+	if gotEvent != "organization.created" {
+		t.Fatalf("expected event header, got %q", gotEvent)
+	}
+}
+
+func TestDispatcherSchedulesRetryOnFailure(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer receiver.Close()
+
+	store := &fakeStore{
+		due: []*repository.ClaimedDelivery{
+			delivery("retry", receiver.URL, 0),
+			delivery("final", receiver.URL, 2),
+		},
+		outcomes: map[string]repository.AttemptOutcome{},
+	}
+	newTestDispatcher(store).drain(context.Background())
+
+	retry := store.outcomes["retry"]
+	if retry.Succeeded || retry.RetryAt == nil {
+		t.Fatalf("expected retry to be scheduled, got %+v", retry)
+	}
+	if retry.StatusCode != http.StatusInternalServerError || retry.Error == "" {
+		t.Fatalf("expected status and error to be recorded, got %+v", retry)
+	}
+	if retry.DisableAfter != 10 {
+		t.Fatalf("expected DisableAfter to be passed through, got %d", retry.DisableAfter)
+	}
+
+	final := store.outcomes["final"]
+	if final.Succeeded || final.RetryAt != nil {
+		t.Fatalf("expected no retry after max attempts, got %+v", final)
+	}
+}
+
+func TestDispatcherRecordsConnectionErrors(t *testing.T) {
+	store := &fakeStore{
+		due:      []*repository.ClaimedDelivery{delivery("d1", "http://127.0.0.1:1", 0)},
+		outcomes: map[string]repository.AttemptOutcome{},
+	}
+	newTestDispatcher(store).drain(context.Background())
+
+	o := store.outcomes["d1"]
+	if o.Succeeded || o.StatusCode != 0 || o.Error != "connection failed" {
+		t.Fatalf("expected a generic connection error to be recorded, got %+v", o)
+	}
+}
+
+func TestDispatcherRefusesInternalAddresses(t *testing.T) {
+	var called bool
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		called = true
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	// localhost only fails once resolved, in the dialer.
+	url := strings.Replace(receiver.URL, "127.0.0.1", "localhost", 1)
+	store := &fakeStore{
+		due:      []*repository.ClaimedDelivery{delivery("d1", url, 0)},
+		outcomes: map[string]repository.AttemptOutcome{},
+	}
+	NewDispatcher(testConfig(), store).drain(context.Background())
+
+	o := store.outcomes["d1"]
+	if called || o.Succeeded || o.Error != ErrAddressNotAllowed.Error() {
+		t.Fatalf("expected delivery to be refused, got %+v (receiver called: %t)", o, called)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	cases := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, 10 * time.Second},
+		{2, 20 * time.Second},
+		{3, 40 * time.Second},
+		{10, time.Minute},
+	}
+	for _, tc := range cases {
+		if got := Backoff(10*time.Second, time.Minute, tc.attempt); got != tc.want {
+			t.Errorf("Backoff(attempt=%d) = %v, want %v", tc.attempt, got, tc.want)
+		}
+	}
+}
+
+func TestDispatcherCheck(t *testing.T) {
+	d := NewDispatcher(testConfig(), &fakeStore{outcomes: map[string]repository.AttemptOutcome{}})
+	if err := d.Check(context.Background()); err == nil {
+		t.Fatal("expected check to fail before the first poll")
+	}
+	d.drain(context.Background())
+	if err := d.Check(context.Background()); err != nil {
+		t.Fatalf("expected check to pass after polling: %v", err)
+	}
+	d.lastPoll.Store(time.Now().Add(-11 * time.Hour).UnixNano())
+	if err := d.Check(context.Background()); err == nil {
+		t.Fatal("expected check to fail when polling has stalled")
+	}
+}
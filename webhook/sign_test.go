@@ -0,0 +1,50 @@
+package webhook
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSignVerifyRoundTrip(t *testing.T) {
+	now := time.Now()
+	body := []byte(`{"type":"organization.created"}`)
+	sig := Sign("whsec_test", now, body)
+
+	if err := Verify("whsec_test", sig, strconv.FormatInt(now.Unix(), 10), body, time.Minute); err != nil {
+		t.Fatalf("expected valid signature, got %v", err)
+	}
+}
+
+func TestVerifyRejectsTamperedBody(t *testing.T) {
+	now := time.Now()
+	sig := Sign("whsec_test", now, []byte(`{"a":1}`))
+
+	err := Verify("whsec_test", sig, strconv.FormatInt(now.Unix(), 10), []byte(`{"a":2}`), time.Minute)
+	if !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("expected ErrInvalidSignature, got %v", err)
+	}
+}
+
+func TestVerifyRejectsWrongSecret(t *testing.T) {
+	now := time.Now()
+	body := []byte(`{}`)
+	sig := Sign("whsec_a", now, body)
+
+	err := Verify("whsec_b", sig, strconv.FormatInt(now.Unix(), 10), body, time.Minute)
+	if !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("expected ErrInvalidSignature, got %v", err)
+	}
+}
+
+func TestVerifyRejectsStaleTimestamp(t *testing.T) {
+	old := time.Now().Add(-time.Hour)
+	body := []byte(`{}`)
+	sig := Sign("whsec_test", old, body)
+
+	err := Verify("whsec_test", sig, strconv.FormatInt(old.Unix(), 10), body, 5*time.Minute)
+	if !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("expected ErrInvalidSignature, got %v", err)
+	}
+}